@@ -17,6 +17,8 @@ import (
 	"github.com/idanhaitner/terraform-provider-noname/internal/experimental/nullable"
 	"github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway"
 	apigatewayintegration "github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway-integration"
+	"github.com/idanhaitner/terraform-provider-noname/internal/service/firehose"
+	"github.com/idanhaitner/terraform-provider-noname/internal/service/logs"
 	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
 	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
 	"github.com/idanhaitner/terraform-provider-noname/names"
@@ -242,12 +244,17 @@ func New(_ context.Context) (*schema.Provider, error) {
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"noname_api_gateway": apigateway.DataSourceApiGateway(),
+			"noname_api_gateway":           apigateway.DataSourceApiGateway(),
+			"noname_api_gateway_export":    apigateway.DataSourceApiGatewayExport(),
+			"noname_api_gateway_inventory": apigateway.DataSourceApiGatewayInventory(),
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"noname_api_gateway":             apigateway.ResourceApiGateway(),
-			"noname_api_gateway_integration": apigatewayintegration.ResourceApiGatewayIntegration(),
+			"noname_api_gateway":                 apigateway.ResourceApiGateway(),
+			"noname_api_gateway_account_logging": apigateway.ResourceApiGatewayAccountLogging(),
+			"noname_api_gateway_integration":     apigatewayintegration.ResourceApiGatewayIntegration(),
+			"noname_firehose_delivery":           firehose.ResourceFirehoseDelivery(),
+			"noname_log_forwarding":              logs.ResourceLogForwarding(),
 		},
 	}
 
@@ -316,7 +323,7 @@ func providerConfigure(ctx context.Context, d *schema.ResourceData, terraformVer
 	}
 
 	if l, ok := d.Get("assume_role").([]interface{}); ok && len(l) > 0 && l[0] != nil {
-		config.AssumeRole = expandAssumeRole(l[0].(map[string]interface{}))
+		config.AssumeRole = conns.ExpandAssumeRole(l[0].(map[string]interface{}))
 		log.Printf("[INFO] assume_role configuration set: (ARN: %q, SessionID: %q, ExternalID: %q)", config.AssumeRole.RoleARN, config.AssumeRole.SessionName, config.AssumeRole.ExternalID)
 	}
 
@@ -504,75 +511,6 @@ func endpointsSchema() *schema.Schema {
 	}
 }
 
-func expandAssumeRole(m map[string]interface{}) *awsbase.AssumeRole {
-	assumeRole := awsbase.AssumeRole{}
-
-	if v, ok := m["duration"].(string); ok && v != "" {
-		duration, _ := time.ParseDuration(v)
-		assumeRole.Duration = duration
-	}
-
-	if v, ok := m["duration_seconds"].(int); ok && v != 0 {
-		assumeRole.Duration = time.Duration(v) * time.Second
-	}
-
-	if v, ok := m["external_id"].(string); ok && v != "" {
-		assumeRole.ExternalID = v
-	}
-
-	if v, ok := m["policy"].(string); ok && v != "" {
-		assumeRole.Policy = v
-	}
-
-	if policyARNSet, ok := m["policy_arns"].(*schema.Set); ok && policyARNSet.Len() > 0 {
-		for _, policyARNRaw := range policyARNSet.List() {
-			policyARN, ok := policyARNRaw.(string)
-
-			if !ok {
-				continue
-			}
-
-			assumeRole.PolicyARNs = append(assumeRole.PolicyARNs, policyARN)
-		}
-	}
-
-	if v, ok := m["role_arn"].(string); ok && v != "" {
-		assumeRole.RoleARN = v
-	}
-
-	if v, ok := m["session_name"].(string); ok && v != "" {
-		assumeRole.SessionName = v
-	}
-
-	if tagMapRaw, ok := m["tags"].(map[string]interface{}); ok && len(tagMapRaw) > 0 {
-		assumeRole.Tags = make(map[string]string)
-
-		for k, vRaw := range tagMapRaw {
-			v, ok := vRaw.(string)
-
-			if !ok {
-				continue
-			}
-
-			assumeRole.Tags[k] = v
-		}
-	}
-
-	if transitiveTagKeySet, ok := m["transitive_tag_keys"].(*schema.Set); ok && transitiveTagKeySet.Len() > 0 {
-		for _, transitiveTagKeyRaw := range transitiveTagKeySet.List() {
-			transitiveTagKey, ok := transitiveTagKeyRaw.(string)
-
-			if !ok {
-				continue
-			}
-
-			assumeRole.TransitiveTagKeys = append(assumeRole.TransitiveTagKeys, transitiveTagKey)
-		}
-	}
-
-	return &assumeRole
-}
-
 func expandAssumeRoleWithWebIdentity(m map[string]interface{}) *awsbase.AssumeRoleWithWebIdentity {
 	assumeRole := awsbase.AssumeRoleWithWebIdentity{}
 
@@ -0,0 +1,37 @@
+package apigatewayintegration
+
+import (
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+const (
+	accessLogFormatNonameJSONV1 = "noname_json_v1"
+	accessLogFormatNonameJSONV2 = "noname_json_v2"
+	accessLogFormatCLF          = "clf"
+	accessLogFormatXML          = "xml"
+	accessLogFormatCSV          = "csv"
+)
+
+var accessLogFormatPresets = map[string]string{
+	accessLogFormatNonameJSONV1: `{"requestId":"$context.requestId","ip":"$context.identity.sourceIp","caller":"$context.identity.caller","user":"$context.identity.user","requestTime":"$context.requestTime","httpMethod":"$context.httpMethod","path":"$context.path","status":"$context.status","protocol":"$context.protocol","responseLength":"$context.responseLength","domainName":"$context.domainName","accountId":"$context.accountId"}`,
+	accessLogFormatNonameJSONV2: `{"requestId":"$context.requestId","extendedRequestId":"$context.extendedRequestId","ip":"$context.identity.sourceIp","userAgent":"$context.identity.userAgent","caller":"$context.identity.caller","user":"$context.identity.user","principalId":"$context.authorizer.principalId","requestTime":"$context.requestTime","requestTimeEpoch":"$context.requestTimeEpoch","httpMethod":"$context.httpMethod","resourcePath":"$context.resourcePath","path":"$context.path","status":"$context.status","protocol":"$context.protocol","responseLength":"$context.responseLength","responseLatency":"$context.responseLatency","integrationLatency":"$context.integrationLatency","errorMessage":"$context.error.message","domainName":"$context.domainName","apiId":"$context.apiId","stage":"$context.stage","accountId":"$context.accountId"}`,
+	accessLogFormatCLF:          `$context.identity.sourceIp $context.identity.caller $context.identity.user [$context.requestTime] "$context.httpMethod $context.resourcePath $context.protocol" $context.status $context.responseLength $context.requestId`,
+	accessLogFormatXML:          `<request id="$context.requestId"> <ip>$context.identity.sourceIp</ip> <caller>$context.identity.caller</caller> <user>$context.identity.user</user> <requestTime>$context.requestTime</requestTime> <httpMethod>$context.httpMethod</httpMethod> <resourcePath>$context.resourcePath</resourcePath> <status>$context.status</status> <protocol>$context.protocol</protocol> <responseLength>$context.responseLength</responseLength> </request>`,
+	accessLogFormatCSV:          `$context.identity.sourceIp,$context.identity.caller,$context.identity.user,$context.requestTime,$context.httpMethod,$context.resourcePath,$context.protocol,$context.status,$context.responseLength,$context.requestId`,
+}
+
+// expandAccessLogFormat returns the template of a preset name, or the value
+// itself for a custom template.
+func expandAccessLogFormat(v string) string {
+	if format, ok := accessLogFormatPresets[v]; ok {
+		return format
+	}
+	return v
+}
+
+func validAccessLogFormat(v interface{}, k string) (ws []string, errors []error) {
+	if _, ok := accessLogFormatPresets[v.(string)]; ok {
+		return
+	}
+	return verify.ValidAPIGatewayAccessLogFormat(v, k)
+}
@@ -0,0 +1,163 @@
+package apigatewayintegration
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	"github.com/idanhaitner/terraform-provider-noname/internal/flex"
+)
+
+// restApiSelector matches the REST APIs integrated through the selector
+// block. Every set criterion has to match.
+type restApiSelector struct {
+	tags          map[string]string
+	nameRegex     *regexp.Regexp
+	endpointTypes []string
+	createdAfter  time.Time
+	createdBefore time.Time
+}
+
+func expandRestApiSelector(tfList []interface{}) (*restApiSelector, error) {
+	if len(tfList) == 0 || tfList[0] == nil {
+		return nil, nil
+	}
+
+	tfMap := tfList[0].(map[string]interface{})
+	selector := &restApiSelector{
+		tags: make(map[string]string),
+	}
+
+	for k, v := range tfMap["tags"].(map[string]interface{}) {
+		selector.tags[k] = v.(string)
+	}
+
+	if v := tfMap["name_regex"].(string); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("compiling selector name_regex (%s): %w", v, err)
+		}
+		selector.nameRegex = re
+	}
+
+	if v, ok := tfMap["endpoint_types"].(*schema.Set); ok {
+		selector.endpointTypes = aws.StringValueSlice(flex.ExpandStringSet(v))
+	}
+
+	for key, t := range map[string]*time.Time{"created_after": &selector.createdAfter, "created_before": &selector.createdBefore} {
+		if v := tfMap[key].(string); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return nil, fmt.Errorf("parsing selector %s (%s): %w", key, v, err)
+			}
+			*t = parsed
+		}
+	}
+
+	return selector, nil
+}
+
+// match reports whether the REST API matches every criterion of the selector.
+// A tag with an empty value matches any value of the key.
+func (s *restApiSelector) match(api *apigateway.RestApi) bool {
+	for k, v := range s.tags {
+		actual, ok := api.Tags[k]
+		if !ok || (v != "" && aws.StringValue(actual) != v) {
+			return false
+		}
+	}
+
+	if s.nameRegex != nil && !s.nameRegex.MatchString(aws.StringValue(api.Name)) {
+		return false
+	}
+
+	if len(s.endpointTypes) > 0 {
+		var types []string
+		if api.EndpointConfiguration != nil {
+			types = aws.StringValueSlice(api.EndpointConfiguration.Types)
+		}
+		if !containsAny(s.endpointTypes, types) {
+			return false
+		}
+	}
+
+	createdDate := aws.TimeValue(api.CreatedDate)
+	if !s.createdAfter.IsZero() && !createdDate.After(s.createdAfter) {
+		return false
+	}
+	if !s.createdBefore.IsZero() && !createdDate.Before(s.createdBefore) {
+		return false
+	}
+
+	return true
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, v := range values {
+		for _, candidate := range candidates {
+			if v == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveRestApiSelector returns the sorted IDs of the REST APIs of the
+// account matching the selector.
+func resolveRestApiSelector(conn *apigateway.APIGateway, selector *restApiSelector) ([]string, error) {
+	apis, err := FindRestApis(conn)
+	if err != nil {
+		return nil, fmt.Errorf("listing API Gateway REST APIs: %w", err)
+	}
+
+	restApiIds := []string{}
+	for _, api := range apis {
+		if selector.match(api) {
+			restApiIds = append(restApiIds, aws.StringValue(api.Id))
+		}
+	}
+	sort.Strings(restApiIds)
+	return restApiIds, nil
+}
+
+// resourceApiGatewayIntegrationSelectorDiff resolves the selector at plan
+// time, so REST APIs that start or stop matching it show up as changes of
+// selected_rest_api_ids.
+func resourceApiGatewayIntegrationSelectorDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	selector, err := expandRestApiSelector(d.Get("selector").([]interface{}))
+	if err != nil {
+		return err
+	}
+
+	restApiIds := []string{}
+	if selector != nil {
+		restApiIds, err = resolveRestApiSelector(meta.(*conns.AWSClient).APIGatewayConn, selector)
+		if err != nil {
+			return err
+		}
+	}
+
+	current := d.Get("selected_rest_api_ids").(*schema.Set)
+	resolved := flex.FlattenStringSet(aws.StringSlice(restApiIds))
+	if current.Equal(resolved) {
+		return nil
+	}
+	return d.SetNew("selected_rest_api_ids", resolved)
+}
+
+// restApiIdsChange returns the REST APIs integrated before and after the
+// change, listed in rest_api_ids or api blocks, or resolved from the selector.
+func restApiIdsChange(d resourceData) (*schema.Set, *schema.Set) {
+	oIds, nIds := d.GetChange("rest_api_ids")
+	oSelected, nSelected := d.GetChange("selected_rest_api_ids")
+	oApis, nApis := d.GetChange("api")
+	return oIds.(*schema.Set).Union(oSelected.(*schema.Set)).Union(apiIds(oApis.(*schema.Set))),
+		nIds.(*schema.Set).Union(nSelected.(*schema.Set)).Union(apiIds(nApis.(*schema.Set)))
+}
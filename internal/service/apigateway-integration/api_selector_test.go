@@ -0,0 +1,90 @@
+package apigatewayintegration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func TestRestApiSelectorMatch(t *testing.T) {
+	selector, err := expandRestApiSelector([]interface{}{
+		map[string]interface{}{
+			"tags":           map[string]interface{}{"team": "payments", "noname": ""},
+			"name_regex":     "^payments-",
+			"endpoint_types": schema.NewSet(schema.HashString, []interface{}{"REGIONAL", "PRIVATE"}),
+			"created_after":  "2022-01-01T00:00:00Z",
+			"created_before": "",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	api := func(name string, tags map[string]string, endpointType string, created string) *apigateway.RestApi {
+		createdDate, _ := time.Parse(time.RFC3339, created)
+		return &apigateway.RestApi{
+			Name:        aws.String(name),
+			Tags:        aws.StringMap(tags),
+			CreatedDate: aws.Time(createdDate),
+			EndpointConfiguration: &apigateway.EndpointConfiguration{
+				Types: aws.StringSlice([]string{endpointType}),
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		api      *apigateway.RestApi
+		expected bool
+	}{
+		{
+			name:     "matching",
+			api:      api("payments-public", map[string]string{"team": "payments", "noname": "true"}, "REGIONAL", "2022-06-01T00:00:00Z"),
+			expected: true,
+		},
+		{
+			name:     "tag value",
+			api:      api("payments-public", map[string]string{"team": "search", "noname": "true"}, "REGIONAL", "2022-06-01T00:00:00Z"),
+			expected: false,
+		},
+		{
+			name:     "missing tag",
+			api:      api("payments-public", map[string]string{"team": "payments"}, "REGIONAL", "2022-06-01T00:00:00Z"),
+			expected: false,
+		},
+		{
+			name:     "name",
+			api:      api("internal-payments", map[string]string{"team": "payments", "noname": "true"}, "REGIONAL", "2022-06-01T00:00:00Z"),
+			expected: false,
+		},
+		{
+			name:     "endpoint type",
+			api:      api("payments-public", map[string]string{"team": "payments", "noname": "true"}, "EDGE", "2022-06-01T00:00:00Z"),
+			expected: false,
+		},
+		{
+			name:     "creation date",
+			api:      api("payments-public", map[string]string{"team": "payments", "noname": "true"}, "PRIVATE", "2021-06-01T00:00:00Z"),
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		if actual := selector.match(tc.api); actual != tc.expected {
+			t.Errorf("%s: match = %t, expected %t", tc.name, actual, tc.expected)
+		}
+	}
+}
+
+func TestExpandRestApiSelector_empty(t *testing.T) {
+	selector, err := expandRestApiSelector([]interface{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if selector != nil {
+		t.Fatalf("expected no selector, got %v", selector)
+	}
+}
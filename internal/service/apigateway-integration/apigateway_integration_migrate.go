@@ -0,0 +1,86 @@
+package apigatewayintegration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+func resourceApiGatewayIntegrationResourceV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"rest_api_ids": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Required: true,
+			},
+			"rest_api_states": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
+		},
+	}
+}
+
+// resourceApiGatewayIntegrationStateUpgradeV0 converts the "<rest_api_id>-<stage>" =>
+// "<data_trace>!<logging_level>!<format>!<destination_arn>" map into the rest_api_states block list.
+func resourceApiGatewayIntegrationStateUpgradeV0(_ context.Context, rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	if rawState == nil {
+		rawState = map[string]interface{}{}
+	}
+
+	v0States, _ := rawState["rest_api_states"].(map[string]interface{})
+	identifiers := make([]string, 0, len(v0States))
+	for identifier := range v0States {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	states := []StageState{}
+	for _, identifier := range identifiers {
+		state, err := parseStageStateV0(identifier, v0States[identifier].(string))
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	rawState["rest_api_states"] = flattenStageStates(states)
+	return rawState, nil
+}
+
+// parseStageStateV0 parses a version 0 snapshot. Those never recorded
+// metrics, which restoring then leaves as they are.
+func parseStageStateV0(identifier string, value string) (StageState, error) {
+	// REST API IDs never contain "-", stage names may.
+	restApiId, stageName, ok := strings.Cut(identifier, "-")
+	if !ok {
+		return StageState{}, fmt.Errorf("unexpected format of rest_api_states key (%s), expected <rest_api_id>-<stage>", identifier)
+	}
+
+	// The access log format may itself contain "!", everything between the
+	// logging level and the destination ARN belongs to it.
+	details := strings.Split(value, "!")
+	if len(details) < 4 {
+		return StageState{}, fmt.Errorf("unexpected format of rest_api_states value for %s: %q", identifier, value)
+	}
+	format := strings.Join(details[2:len(details)-1], "!")
+	destinationArn := details[len(details)-1]
+	if format == "NO" && destinationArn == "NO" {
+		format, destinationArn = "", ""
+	}
+
+	return StageState{
+		restApiId:                restApiId,
+		stageName:                stageName,
+		dataTraceEnabled:         details[0] == "true",
+		loggingLevel:             details[1],
+		accessLogsFormat:         format,
+		accessLogsDestinationArn: destinationArn,
+		metricsUnknown:           true,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package apigatewayintegration
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResourceApiGatewayIntegrationStateUpgradeV0(t *testing.T) {
+	rawState := map[string]interface{}{
+		"id":           "2c3a5c36-5b9f-4bc4-a32f-3c6a4a1b5b61",
+		"rest_api_ids": []interface{}{"a1b2c3d4e5", "f6g7h8i9j0"},
+		"rest_api_states": map[string]interface{}{
+			"f6g7h8i9j0-prod":       "false!OFF!NO!NO",
+			"a1b2c3d4e5-dev-canary": "true!ERROR!$context.requestId !$context.status!arn:aws:logs:us-east-1:123456789012:log-group:access", //lintignore:AWSAT003,AWSAT005
+		},
+	}
+
+	expected := []interface{}{
+		map[string]interface{}{
+			"rest_api_id":                "a1b2c3d4e5",
+			"stage":                      "dev-canary",
+			"logging_level":              "ERROR",
+			"data_trace_enabled":         true,
+			"metrics_enabled":            false,
+			"access_log_format":          "$context.requestId !$context.status",
+			"access_log_destination_arn": "arn:aws:logs:us-east-1:123456789012:log-group:access", //lintignore:AWSAT003,AWSAT005
+			"region":                     "",
+			"account_id":                 "",
+			"assume_role_arn":            "",
+			"assume_role_external_id":    "",
+			"assume_role_session_name":   "",
+			"wildcard_absent":            false,
+			"metrics_unknown":            true,
+			"method_settings":            []interface{}{},
+			"applied_configuration":      []interface{}{},
+		},
+		map[string]interface{}{
+			"rest_api_id":                "f6g7h8i9j0",
+			"stage":                      "prod",
+			"logging_level":              "OFF",
+			"data_trace_enabled":         false,
+			"metrics_enabled":            false,
+			"access_log_format":          "",
+			"access_log_destination_arn": "",
+			"region":                     "",
+			"account_id":                 "",
+			"assume_role_arn":            "",
+			"assume_role_external_id":    "",
+			"assume_role_session_name":   "",
+			"wildcard_absent":            false,
+			"metrics_unknown":            true,
+			"method_settings":            []interface{}{},
+			"applied_configuration":      []interface{}{},
+		},
+	}
+
+	actual, err := resourceApiGatewayIntegrationStateUpgradeV0(context.Background(), rawState, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual["rest_api_states"]) {
+		t.Fatalf("Got:\n\n%#v\n\nExpected:\n\n%#v", actual["rest_api_states"], expected)
+	}
+}
+
+func TestResourceApiGatewayIntegrationStateUpgradeV0_invalid(t *testing.T) {
+	rawState := map[string]interface{}{
+		"rest_api_states": map[string]interface{}{
+			"a1b2c3d4e5-prod": "true!INFO",
+		},
+	}
+
+	if _, err := resourceApiGatewayIntegrationStateUpgradeV0(context.Background(), rawState, nil); err == nil {
+		t.Fatal("expected error, got none")
+	}
+}
+
+// Version 0 snapshots did not record metrics, destroying after the upgrade
+// leaves them as they are.
+func TestResourceApiGatewayIntegrationStateUpgradeV0_restore(t *testing.T) {
+	rawState := map[string]interface{}{
+		"rest_api_ids": []interface{}{"a1b2c3d4e5"},
+		"rest_api_states": map[string]interface{}{
+			"a1b2c3d4e5-prod": "false!ERROR!NO!NO",
+		},
+	}
+
+	actual, err := resourceApiGatewayIntegrationStateUpgradeV0(context.Background(), rawState, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fake := &fakeAPIGateway{
+		stages:           []string{"prod"},
+		configuredStages: []string{"prod"},
+		updates:          make(map[string][]string),
+		throttled:        make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+	in.restorePolicy = restorePolicySafe
+
+	result := in.deconfigureRestApi("a1b2c3d4e5", expandStageStates(actual["rest_api_states"].([]interface{})))
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+	if updates := fake.updates["a1b2c3d4e5"]; !reflect.DeepEqual(updates, []string{"prod"}) {
+		t.Fatalf("stage updates: got %v, expected [prod]", updates)
+	}
+	for _, path := range fake.patchPaths {
+		if strings.HasSuffix(path, "/metrics/enabled") {
+			t.Errorf("unexpected patch operation of %s", path)
+		}
+	}
+}
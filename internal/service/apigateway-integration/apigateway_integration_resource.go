@@ -1,221 +1,1084 @@
 package apigatewayintegration
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"regexp"
 	"strings"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/apigateway"
-	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/customdiff"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
-	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
 )
 
-type StageState struct {
+// NonameLoggingLevel is the execution logging level of the Noname logging
+// configuration.
+const NonameLoggingLevel = "INFO"
+
+const (
+	stageComplianceCompliant = "COMPLIANT"
+	stageComplianceDrifted   = "DRIFTED"
+	stageComplianceUnmanaged = "UNMANAGED"
+)
+
+// stageConfiguration is the Noname logging configuration applied to a stage.
+type stageConfiguration struct {
+	loggingLevel             string
 	dataTraceEnabled         bool
+	accessLogsFormat         string
+	accessLogsDestinationArn string
+	forceMethodLogging       bool
+}
+
+// StageState is the snapshot of a stage's logging settings taken before the
+// Noname logging configuration is applied. Empty access log fields mean the
+// stage had no access logging configured. The wildcard method settings are
+// kept in the top level fields, the method overrides in methodSettings. An
+// empty region is the provider region. accountId is the account the snapshot
+// was taken in, the assumeRole fields the role assumed to reach it, if any.
+// metricsUnknown marks snapshots upgraded from version 0 states, which did not
+// record metrics. applied is the configuration last applied to the stage, which restoring
+// compares the stage with. It is nil for snapshots rebuilt from the snapshot
+// store or written before it was recorded.
+type StageState struct {
+	restApiId                string
+	stageName                string
 	loggingLevel             string
+	dataTraceEnabled         bool
+	metricsEnabled           bool
 	accessLogsFormat         string
 	accessLogsDestinationArn string
+	region                   string
+	accountId                string
+	assumeRoleArn            string
+	assumeRoleExternalId     string
+	assumeRoleSessionName    string
+	wildcardAbsent           bool
+	metricsUnknown           bool
+	methodSettings           []methodSettingsState
+	applied                  *stageConfiguration
 }
 
 func ResourceApiGatewayIntegration() *schema.Resource {
 	return &schema.Resource{
 		Description: `Use this data source to get the access to the effective
 		Account ID, User ID, ARN and EKS Role ARN in which Terraform is authorized.`,
-		Read:   resourceApiGatewayIntegrationRead,
-		Create: resourceApiGatewayIntegrationCreate,
-		Delete: resourceApiGatewayIntegrationDelete,
-		Update: resourceApiGatewayIntegrationUpdate,
+		ReadContext:   resourceApiGatewayIntegrationRead,
+		CreateContext: resourceApiGatewayIntegrationCreate,
+		DeleteContext: resourceApiGatewayIntegrationDelete,
+		UpdateContext: resourceApiGatewayIntegrationUpdate,
+		Importer: &schema.ResourceImporter{
+			State: resourceApiGatewayIntegrationImport,
+		},
+
+		CustomizeDiff: customdiff.Sequence(
+			resourceApiGatewayIntegrationSelectorDiff,
+			resourceApiGatewayIntegrationPlannedChangesDiff,
+		),
+
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Type:    resourceApiGatewayIntegrationResourceV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceApiGatewayIntegrationStateUpgradeV0,
+				Version: 0,
+			},
+		},
+
 		Schema: map[string]*schema.Schema{
 			"rest_api_ids": {
-				Description: `AWS Account ID number of the account that owns or contains the calling entity.`,
+				Description:  `AWS Account ID number of the account that owns or contains the calling entity.`,
+				Type:         schema.TypeSet,
+				Elem:         &schema.Schema{Type: schema.TypeString},
+				Optional:     true,
+				AtLeastOneOf: []string{"rest_api_ids", "selector", "api"},
+			},
+			"api": {
+				Description: "REST API to integrate, optionally in another region or account than the provider's.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Description: "ID of the REST API.",
+							Type:        schema.TypeString,
+							Required:    true,
+						},
+						"region": {
+							Description: "Region of the REST API. Defaults to the provider region.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+						"assume_role": {
+							Description: "IAM Role assumed to reach the REST API in another account. Defaults to the provider credentials.",
+							Type:        schema.TypeList,
+							Optional:    true,
+							MaxItems:    1,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"role_arn": {
+										Description:  "Amazon Resource Name (ARN) of the IAM Role to assume.",
+										Type:         schema.TypeString,
+										Required:     true,
+										ValidateFunc: verify.ValidARN,
+									},
+									"external_id": {
+										Description: "A unique identifier that might be required when you assume a role in another account.",
+										Type:        schema.TypeString,
+										Optional:    true,
+										ValidateFunc: validation.All(
+											validation.StringLenBetween(2, 1224),
+											validation.StringMatch(regexp.MustCompile(`[\w+=,.@:\/\-]*`), ""),
+										),
+									},
+									"session_name": {
+										Description: "An identifier for the assumed role session.",
+										Type:        schema.TypeString,
+										Optional:    true,
+										ValidateFunc: validation.All(
+											validation.StringLenBetween(2, 64),
+											validation.StringMatch(regexp.MustCompile(`[\w+=,.@\-]*`), ""),
+										),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"selector": {
+				Description: "Integrates every REST API of the provider region matching all of the set criteria, in addition to `rest_api_ids`. " +
+					"The selector is resolved on every plan.",
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"tags": {
+							Description: "Tags the REST API must have. An empty value matches any value of the tag.",
+							Type:        schema.TypeMap,
+							Optional:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+						"name_regex": {
+							Description:  "Regular expression the REST API name must match.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.StringIsValidRegExp,
+						},
+						"endpoint_types": {
+							Description: "Endpoint types of the REST API, any of `EDGE`, `REGIONAL` and `PRIVATE`.",
+							Type:        schema.TypeSet,
+							Optional:    true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validation.StringInSlice(apigateway.EndpointType_Values(), false),
+							},
+						},
+						"created_after": {
+							Description:  "RFC3339 timestamp the REST API must have been created after.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.IsRFC3339Time,
+						},
+						"created_before": {
+							Description:  "RFC3339 timestamp the REST API must have been created before.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validation.IsRFC3339Time,
+						},
+					},
+				},
+			},
+			"selected_rest_api_ids": {
+				Description: "IDs of the REST APIs matching the selector.",
 				Type:        schema.TypeSet,
 				Elem:        &schema.Schema{Type: schema.TypeString},
-				Required:    true,
+				Computed:    true,
 			},
-			"rest_api_states": {
-				Description: `List of stages of the API`,
+			"access_log_format": {
+				Description: "Access log format of the configured stages. Either one of the `noname_json_v1`, `noname_json_v2`, `clf`, `xml` and `csv` presets, " +
+					"or a custom single line template of `$context` variables that includes `$context.requestId`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      accessLogFormatNonameJSONV1,
+				ValidateFunc: validAccessLogFormat,
+			},
+			"access_log_destination_arn": {
+				Description: "ARN of an existing CloudWatch Logs log group or Kinesis Data Firehose delivery stream that receives the access logs of every configured stage. " +
+					"When not set, each stage logs to its own `API-Gateway-Execution-Logs_<rest_api_id>/<stage>` log group, which is created when missing.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: verify.ValidARN,
+			},
+			"force_method_logging": {
+				Description: "Also apply the Noname logging configuration to the method overrides of the stages, such as `pets/GET`, " +
+					"which otherwise keep their own logging settings.",
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"data_trace": {
+				Description: "Whether the Noname logging configuration enables data tracing, which writes full request and response bodies to CloudWatch Logs. " +
+					"`always`, `never`, or `tagged` to enable it only on stages, or stages of REST APIs, with one of the `data_trace_tags`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      dataTraceAlways,
+				ValidateFunc: validation.StringInSlice(dataTrace_Values(), false),
+			},
+			"data_trace_tags": {
+				Description: "Tags enabling data tracing on a stage, or on all stages of a REST API, with `data_trace = \"tagged\"`. An empty value matches any value of the tag.",
 				Type:        schema.TypeMap,
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"sensitive_tags": {
+				Description: "Tags marking a stage, or all stages of a REST API, as sensitive. Planning fails when data tracing would be enabled on a sensitive stage. " +
+					"An empty value matches any value of the tag.",
+				Type:     schema.TypeMap,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"restore_policy": {
+				Description: "How stages are restored on destroy, or when they stop being integrated. " +
+					"With `safe`, stages whose logging settings were changed by someone else since the integration configured them are skipped and keep their settings and snapshots, " +
+					"failing the destroy until they are restored with `force`, with which every stage gets its snapshot back.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      restorePolicySafe,
+				ValidateFunc: validation.StringInSlice(restorePolicy_Values(), false),
+			},
+			"takeover": {
+				Description: "Take over stages owned by another integration, as recorded in their `noname:integration-id` tag. " +
+					"Otherwise configuring a stage owned by another integration fails, naming its owner.",
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"enforce_new_stages": {
+				Description: "Plan an update whenever a covered REST API has stages created since the last apply, so a scheduled apply onboards them. " +
+					"Otherwise they are reported in `unmanaged_stages` and onboarded by the next apply.",
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"unmanaged_stages": {
+				Description: `Stages, as "<rest_api_id>-<stage>", matching the stage filter that were created since the last apply.`,
+				Type:        schema.TypeSet,
 				Elem:        &schema.Schema{Type: schema.TypeString},
 				Computed:    true,
 			},
+			"parallelism": {
+				Description:  "Number of REST APIs configured or restored concurrently.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      10,
+				ValidateFunc: validation.IntBetween(1, 50),
+			},
+			"log_group": {
+				Description: "Settings of the log groups created by the integration.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"retention_in_days": {
+							Description:  "Number of days log events are retained. Log events never expire when 0.",
+							Type:         schema.TypeInt,
+							Optional:     true,
+							Default:      0,
+							ValidateFunc: validation.IntInSlice([]int{0, 1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 2192, 2557, 2922, 3288, 3653}),
+						},
+						"kms_key_id": {
+							Description:  "ARN of the KMS key used to encrypt the log groups.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: verify.ValidARN,
+						},
+						"tags": tftags.TagsSchema(),
+						"delete_on_destroy": {
+							Description: "Delete the log groups created by the integration on destroy, or when their REST API is removed from the integration.",
+							Type:        schema.TypeBool,
+							Optional:    true,
+							Default:     false,
+						},
+					},
+				},
+			},
+			"snapshot_store": {
+				Description: "Where the snapshots of the stages are also kept, outside of the Terraform state, so destroy and import can rebuild them after the state is lost.",
+				Type:        schema.TypeList,
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"type": {
+							Description: "`ssm` for an SSM parameter per stage in the account and region of its REST API, " +
+								"`stage_tag` for `noname:snapshot:<n>` tags on the stage, which share the limit of 50 tags with the own tags of the stage, " +
+								"or `file` for a local file per stage.",
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(snapshotStore_Values(), false),
+						},
+						"ssm_prefix": {
+							Description: "Path prefix of the SSM parameters, named `<ssm_prefix>/<rest_api_id>/<stage>`.",
+							Type:        schema.TypeString,
+							Optional:    true,
+							Default:     defaultSnapshotStoreSSMPrefix,
+							ValidateFunc: validation.StringMatch(regexp.MustCompile(`^/[a-zA-Z0-9_.\-/]*$`),
+								"must be a path starting with /"),
+						},
+						"directory": {
+							Description: "Directory of the snapshot files, named `<directory>/<rest_api_id>/<stage>.json`. Required with `file`.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+					},
+				},
+			},
+			"managed_log_groups": {
+				Description: "Names of the log groups created by the integration.",
+				Type:        schema.TypeSet,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Computed:    true,
+			},
+			"log_group_names": {
+				Description: "Names of the execution and access log groups of the configured stages in the provider account and region, " +
+					"such as to forward them with `noname_log_forwarding`.",
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
+			"stage_include": {
+				Description: "Glob patterns, or regular expressions wrapped in `/`, of the stages to configure. All stages are configured when empty.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validStagePattern,
+				},
+			},
+			"stage_exclude": {
+				Description: "Glob patterns, or regular expressions wrapped in `/`, of the stages that are never configured.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validStagePattern,
+				},
+			},
+			"rest_api_stage": {
+				Description: "Stages to configure for a single REST API. Takes precedence over `stage_include` for that REST API.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rest_api_id": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"stages": {
+							Description: "Stage names or patterns, in the same format as `stage_include`.",
+							Type:        schema.TypeSet,
+							Required:    true,
+							Elem: &schema.Schema{
+								Type:         schema.TypeString,
+								ValidateFunc: validStagePattern,
+							},
+						},
+					},
+				},
+			},
+			"rest_api_states": {
+				Description: `Settings of each stage before the Noname logging configuration was applied. They are restored when the REST API is removed from the integration.`,
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rest_api_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"stage": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"logging_level": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"data_trace_enabled": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"metrics_enabled": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"access_log_format": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"access_log_destination_arn": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"region": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"account_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"assume_role_arn": {
+							Description: "IAM Role assumed to reach the REST API, kept so it is restored with the same credentials once its api block is gone.",
+							Type:        schema.TypeString,
+							Computed:    true,
+						},
+						"assume_role_external_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"assume_role_session_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"wildcard_absent": {
+							Description: "Whether the stage had no `*/*` method settings, which are then removed on restore.",
+							Type:        schema.TypeBool,
+							Computed:    true,
+						},
+						"metrics_unknown": {
+							Description: "Whether the snapshot was taken before metrics were recorded, metrics are then left as they are on restore.",
+							Type:        schema.TypeBool,
+							Computed:    true,
+						},
+						"method_settings": {
+							Description: "Settings of the method overrides of the stage.",
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"method_path": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"logging_level": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"data_trace_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"metrics_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+								},
+							},
+						},
+						"applied_configuration": {
+							Description: "Configuration last applied to the stage, restore_policy compares the stage with it.",
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"logging_level": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"data_trace_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"access_log_format": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"access_log_destination_arn": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"force_method_logging": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"planned_changes": {
+				Description: "Stage updates of the next apply, with the patch operations each stage gets. Cleared on refresh once applied.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"rest_api_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"stage": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"action": {
+							Description: "`configure` when the Noname logging configuration is applied, `restore` when the snapshot is restored, " +
+								"`skip` when the stage was changed by someone else and keeps its settings.",
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"patch_operations": {
+							Description: "Patch operations of the stage update, as `<op> <path> [<value>]`.",
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			"compliance": {
+				Description: `Map of "<rest_api_id>-<stage>" to COMPLIANT or DRIFTED, depending on whether the stage still has the Noname logging configuration applied, ` +
+					`or to UNMANAGED for stages created since the last apply.`,
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
 		},
 	}
 }
 
-func resourceApiGatewayIntegrationRead(d *schema.ResourceData, meta interface{}) error {
-	return nil
+func resourceApiGatewayIntegrationRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// The changes planned for the last apply were made, the next plan
+	// computes the pending ones.
+	d.Set("planned_changes", nil)
+	return readApiGatewayIntegration(ctx, d, meta)
 }
 
-func saveStagesStates(d *schema.ResourceData, conn *apigateway.APIGateway, restApiId string) map[string]interface{} {
-	allStates := d.Get("rest_api_states").(map[string]interface{})
-	res, _ := conn.GetStages(&apigateway.GetStagesInput{
-		RestApiId: &restApiId,
-	})
+// readApiGatewayIntegration refreshes the integration. Create and Update call
+// it directly to keep the planned_changes they applied.
+func readApiGatewayIntegration(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	allStates := expandStageStates(d.Get("rest_api_states").([]interface{}))
+	enforceNewStages := d.Get("enforce_new_stages").(bool)
+	unmanagedStages := schema.NewSet(schema.HashString, nil)
+	compliance := make(map[string]interface{})
+	compliantRestApiIds := schema.NewSet(schema.HashString, nil)
+	restApiIds := d.Get("rest_api_ids").(*schema.Set)
+	selectedRestApiIds := d.Get("selected_rest_api_ids").(*schema.Set)
+	apis := d.Get("api").(*schema.Set)
+	for _, v := range restApiIds.Union(selectedRestApiIds).Union(apiIds(apis)).List() {
+		restApiId := v.(string)
+		clients, err := in.targetClients(in.targetOf(restApiId))
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		stages, err := in.findStages(clients, restApiId)
+
+		if tfresource.NotFound(err) {
+			log.Printf("[WARN] API Gateway REST API (%s) not found, removing from integration %s", restApiId, d.Id())
+			continue
+		}
+
+		// The REST API is kept in state, its stages are checked again on the
+		// next refresh.
+		if err != nil {
+			diags = append(diags, restApiResult{restApiId: restApiId, err: err}.diagnostic("reading stages of"))
+			compliantRestApiIds.Add(restApiId)
+			continue
+		}
+		restApiTags, err := in.restApiTags(clients, restApiId)
+		if err != nil {
+			diags = append(diags, restApiResult{restApiId: restApiId, err: err}.diagnostic("reading tags of"))
+			compliantRestApiIds.Add(restApiId)
+			continue
+		}
+
+		// A REST API with at least one drifted stage is left out of rest_api_ids,
+		// selected_rest_api_ids and api, so the next plan shows it being added
+		// back and Update re-applies it.
+		compliant := true
+		for _, stage := range stages {
+			stageName := aws.StringValue(stage.StageName)
+			identifier := fmt.Sprintf("%v-%v", restApiId, stageName)
+			if !in.filter.match(restApiId, stageName) {
+				// A snapshot of a stage that no longer matches the filter is
+				// still to be restored.
+				if findStageState(allStates, restApiId, stageName) != nil {
+					compliance[identifier] = stageComplianceDrifted
+					compliant = false
+				}
+				continue
+			}
+			// A stage taken over by another integration shows up as drifted,
+			// re-applying it fails naming the owner.
+			if in.ownedByOther(stage) {
+				log.Printf("[WARN] API Gateway REST API (%s) stage (%s) is owned by noname_api_gateway_integration (%s)", restApiId, stageName, stageOwner(stage))
+				compliance[identifier] = stageComplianceDrifted
+				compliant = false
+				continue
+			}
+			// A stage without snapshot was created since the last apply.
+			if findStageState(allStates, restApiId, stageName) == nil {
+				log.Printf("[WARN] API Gateway REST API (%s) stage (%s) is not managed by the integration yet", restApiId, stageName)
+				compliance[identifier] = stageComplianceUnmanaged
+				unmanagedStages.Add(identifier)
+				if enforceNewStages {
+					compliant = false
+				}
+				continue
+			}
+			drift := stageDrift(stage, in.stageConfiguration(clients, restApiId, stageName, stageTags(restApiTags, stage)))
+			if len(drift) > 0 {
+				log.Printf("[WARN] API Gateway REST API (%s) stage (%s) drifted from the Noname logging configuration: %s", restApiId, stageName, strings.Join(drift, ", "))
+				compliance[identifier] = stageComplianceDrifted
+				compliant = false
+				continue
+			}
+			compliance[identifier] = stageComplianceCompliant
+		}
+		if compliant {
+			compliantRestApiIds.Add(restApiId)
+		}
+	}
 
-	for _, stage := range res.Item {
-		identifier := fmt.Sprintf("%v-%v", restApiId, *stage.StageName)
-		state := extractStageState(stage)
-		allStates[identifier] = fmt.Sprintf("%v!%v!%v!%v",
-			state.dataTraceEnabled,
-			state.loggingLevel,
-			state.accessLogsFormat,
-			state.accessLogsDestinationArn,
-		)
+	d.Set("rest_api_ids", restApiIds.Intersection(compliantRestApiIds))
+	d.Set("selected_rest_api_ids", selectedRestApiIds.Intersection(compliantRestApiIds))
+	compliantApis := []interface{}{}
+	for _, tfMapRaw := range apis.List() {
+		if compliantRestApiIds.Contains(tfMapRaw.(map[string]interface{})["id"]) {
+			compliantApis = append(compliantApis, tfMapRaw)
+		}
 	}
-	return allStates
+	d.Set("api", compliantApis)
+	d.Set("compliance", compliance)
+	d.Set("unmanaged_stages", unmanagedStages)
+	d.Set("log_group_names", in.logGroupNames(allStates))
+	return diags
 }
 
-func generateLogGroup(accountId string, region string, restApiId string, stageName string) string {
-	return fmt.Sprintf("arn:aws:logs:%v:%v:log-group:API-Gateway-Execution-Logs_%v/%v", region, accountId, restApiId, stageName)
+// resourceApiGatewayIntegrationImport imports the integration of the REST
+// APIs of an ID like <rest_api_id>,<rest_api_id>[;<snapshot_store_type>[:<location>]].
+// The snapshots are rebuilt from the snapshot store when one is given.
+func resourceApiGatewayIntegrationImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	idParts := strings.SplitN(d.Id(), ";", 2)
+	restApiIds := []string{}
+	for _, restApiId := range strings.Split(idParts[0], ",") {
+		if restApiId = strings.TrimSpace(restApiId); restApiId != "" {
+			restApiIds = append(restApiIds, restApiId)
+		}
+	}
+	if len(restApiIds) == 0 {
+		return nil, fmt.Errorf("unexpected format of ID (%s), expected <rest_api_id>,<rest_api_id>[;<snapshot_store_type>[:<location>]]", d.Id())
+	}
+
+	if len(idParts) == 2 {
+		tfMap, err := importSnapshotStore(idParts[1])
+		if err != nil {
+			return nil, err
+		}
+		d.Set("snapshot_store", []interface{}{tfMap})
+	}
+
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var allStages []*apigateway.Stage
+	for _, restApiId := range restApiIds {
+		clients, err := in.targetClients(in.targetOf(restApiId))
+		if err != nil {
+			return nil, err
+		}
+		stages, err := FindStagesByRestAPIID(clients.conn, restApiId)
+		if err != nil {
+			return nil, fmt.Errorf("reading API Gateway REST API (%s) stages: %w", restApiId, err)
+		}
+		allStates := expandStageStates(d.Get("rest_api_states").([]interface{}))
+		allStates, err = in.saveStagesStates(clients, allStates, restApiId, stages)
+		if err != nil {
+			return nil, fmt.Errorf("importing API Gateway REST API (%s) stages: %w", restApiId, err)
+		}
+		d.Set("rest_api_states", flattenStageStates(allStates))
+		allStages = append(allStages, stages...)
+	}
+
+	// The imported integration keeps owning the stages claimed by the
+	// integration it replaces.
+	id := importIntegrationId(allStages)
+	if id == "" {
+		id = uuid.New().String()
+	}
+	d.SetId(id)
+	d.Set("rest_api_ids", restApiIds)
+	return []*schema.ResourceData{d}, nil
 }
 
-func extractStageState(stage *apigateway.Stage) StageState {
+// stageDrift returns the settings of the stage that differ from the
+// configuration applied by configureRestApi.
+func stageDrift(stage *apigateway.Stage, config stageConfiguration) []string {
+	var drift []string
+	settings := stage.MethodSettings[WildcardMethodPath]
+	if settings == nil || aws.StringValue(settings.LoggingLevel) != config.loggingLevel {
+		drift = append(drift, "loggingLevel")
+	}
+	if settings == nil || aws.BoolValue(settings.DataTraceEnabled) != config.dataTraceEnabled {
+		drift = append(drift, "dataTrace")
+	}
+	if config.forceMethodLogging {
+		for _, methodPath := range methodOverridePaths(stage) {
+			settings := stage.MethodSettings[methodPath]
+			if methodLoggingLevel(settings) != config.loggingLevel {
+				drift = append(drift, methodPath+".loggingLevel")
+			}
+			if aws.BoolValue(settings.DataTraceEnabled) != config.dataTraceEnabled {
+				drift = append(drift, methodPath+".dataTrace")
+			}
+		}
+	}
+	if stage.AccessLogSettings == nil || aws.StringValue(stage.AccessLogSettings.Format) != config.accessLogsFormat {
+		drift = append(drift, "accessLogSettings.format")
+	}
+	if stage.AccessLogSettings == nil || aws.StringValue(stage.AccessLogSettings.DestinationArn) != config.accessLogsDestinationArn {
+		drift = append(drift, "accessLogSettings.destinationArn")
+	}
+	return drift
+}
+
+func configureStagePatchOperations(stage *apigateway.Stage, config stageConfiguration) []*apigateway.PatchOperation {
+	patchOperations := methodLoggingPatchOperations(WildcardMethodPath, config.loggingLevel, config.dataTraceEnabled)
+	if config.forceMethodLogging {
+		for _, methodPath := range methodOverridePaths(stage) {
+			patchOperations = append(patchOperations, methodLoggingPatchOperations(methodPath, config.loggingLevel, config.dataTraceEnabled)...)
+		}
+	}
+	return append(patchOperations, []*apigateway.PatchOperation{
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String("/accessLogSettings/format"),
+			Value: aws.String(config.accessLogsFormat),
+		},
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String("/accessLogSettings/destinationArn"),
+			Value: aws.String(config.accessLogsDestinationArn),
+		},
+	}...)
+}
+
+func (in *integration) saveStagesStates(clients *targetConns, allStates []StageState, restApiId string, stages []*apigateway.Stage) ([]StageState, error) {
+	for _, stage := range stages {
+		// Keep the snapshot taken when the stage was first configured, the
+		// current settings may be our own drifted configuration.
+		if findStageState(allStates, restApiId, aws.StringValue(stage.StageName)) != nil {
+			continue
+		}
+		state, err := in.initialStageState(clients, restApiId, stage)
+		if err != nil {
+			return nil, err
+		}
+		allStates = append(allStates, *state)
+	}
+	return allStates, nil
+}
+
+func findStageState(states []StageState, restApiId string, stageName string) *StageState {
+	for i := range states {
+		if states[i].restApiId == restApiId && states[i].stageName == stageName {
+			return &states[i]
+		}
+	}
+	return nil
+}
+
+func removeStageState(states []StageState, restApiId string, stageName string) []StageState {
+	remaining := []StageState{}
+	for _, state := range states {
+		if state.restApiId != restApiId || state.stageName != stageName {
+			remaining = append(remaining, state)
+		}
+	}
+	return remaining
+}
+
+func generateLogGroup(partition string, accountId string, region string, restApiId string, stageName string) string {
+	return fmt.Sprintf("arn:%v:logs:%v:%v:log-group:%v", partition, region, accountId, logGroupName(restApiId, stageName))
+}
+
+// extractStageState snapshots the logging settings of the stage. A stage
+// without wildcard method settings logs nothing by default.
+func extractStageState(restApiId string, stage *apigateway.Stage) StageState {
 	format, destinationArn := getAccessLogsSettings(stage.AccessLogSettings)
-	return StageState{
-		dataTraceEnabled:         *stage.MethodSettings["*/*"].DataTraceEnabled,
-		loggingLevel:             *stage.MethodSettings["*/*"].LoggingLevel,
+	state := StageState{
+		restApiId:                restApiId,
+		stageName:                aws.StringValue(stage.StageName),
+		loggingLevel:             loggingLevelOff,
 		accessLogsFormat:         format,
 		accessLogsDestinationArn: destinationArn,
+		wildcardAbsent:           true,
+	}
+	if settings, ok := stage.MethodSettings[WildcardMethodPath]; ok && settings != nil {
+		wildcard := extractMethodSettingsState(WildcardMethodPath, settings)
+		state.loggingLevel = wildcard.loggingLevel
+		state.dataTraceEnabled = wildcard.dataTraceEnabled
+		state.metricsEnabled = wildcard.metricsEnabled
+		state.wildcardAbsent = false
 	}
+	mergeMethodSettingsStates(&state, stage)
+	return state
 }
 
-func resourceApiGatewayIntegrationCreate(d *schema.ResourceData, meta interface{}) error {
-
-	restApiIds := d.Get("rest_api_ids").(*schema.Set)
-	for _, restApiId := range restApiIds.List() {
-		configureRestApi(meta, d, restApiId.(string))
+func resourceApiGatewayIntegrationCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	_, restApiIds := restApiIdsChange(d)
+	if err := in.checkAccountLoggingRoles(sortedRestApiIds(restApiIds)); err != nil {
+		return diag.FromErr(err)
 	}
+
+	// The ID is set first, so the snapshots of the stages changed before a
+	// failure are saved and restored by the next apply or destroy. The
+	// stages are claimed with it.
 	d.SetId(uuid.New().String())
+	in.id = d.Id()
+	if diags := configureRestApis(d, in, sortedRestApiIds(restApiIds)); diags.HasError() {
+		return diags
+	}
+	return readApiGatewayIntegration(ctx, d, meta)
+}
+
+// checkAccountLoggingRoles fails when the API Gateway account settings of
+// the account and region of any of the REST APIs have no CloudWatch Logs
+// role, without which the stages cannot be configured. Each target is checked
+// once, with its own credentials.
+func (in *integration) checkAccountLoggingRoles(restApiIds []string) error {
+	checked := make(map[string]bool)
+	for _, restApiId := range restApiIds {
+		target := in.targetOf(restApiId)
+		if checked[target.key()] {
+			continue
+		}
+		checked[target.key()] = true
+
+		clients, err := in.targetClients(target)
+		if err != nil {
+			return err
+		}
+		if err := checkAccountLoggingRole(clients); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkAccountLoggingRole(clients *targetConns) error {
+	account, err := clients.conn.GetAccount(&apigateway.GetAccountInput{})
+	if err != nil {
+		return fmt.Errorf("reading API Gateway account (%s/%s): %w", clients.accountId, clients.region, err)
+	}
+	if aws.StringValue(account.CloudwatchRoleArn) == "" {
+		return fmt.Errorf("API Gateway account settings of account %s in %s have no CloudWatch Logs role ARN, which %s execution logging requires. "+
+			"Set one with the noname_api_gateway_account_logging resource", clients.accountId, clients.region, NonameLoggingLevel)
+	}
 	return nil
 }
 
 func getAccessLogsSettings(settings *apigateway.AccessLogSettings) (string, string) {
 	if settings == nil {
-		return "NO", "NO"
+		return "", ""
 	}
-	return *settings.Format, *settings.DestinationArn
+	return aws.StringValue(settings.Format), aws.StringValue(settings.DestinationArn)
 }
 
-func configureRestApi(meta interface{}, d *schema.ResourceData, restApiId string) error {
-	stsConn := meta.(*conns.AWSClient).STSConn
-	res, _ := stsConn.GetCallerIdentity(&sts.GetCallerIdentityInput{})
-	accountId := res.Account
-	region := meta.(*conns.AWSClient).Session.Config.Region
-	conn := meta.(*conns.AWSClient).APIGatewayConn
-	allStates := saveStagesStates(d, conn, restApiId)
-	d.Set("rest_api_states", allStates)
-	apiRes, _ := conn.GetStages(&apigateway.GetStagesInput{
-		RestApiId: &restApiId,
-	})
-	for _, stage := range apiRes.Item {
-		conn.UpdateStage(&apigateway.UpdateStageInput{
-			RestApiId: &restApiId,
-			StageName: stage.StageName,
-			PatchOperations: []*apigateway.PatchOperation{
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/*/*/logging/loglevel"),
-					Value: aws.String("INFO"),
-				},
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/*/*/logging/dataTrace"),
-					Value: aws.String("true"),
-				},
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/accessLogSettings/format"),
-					Value: aws.String(`{"requestId":"$context.requestId","ip":"$context.identity.sourceIp","caller":"$context.identity.caller","user":"$context.identity.user","requestTime":"$context.requestTime","httpMethod":"$context.httpMethod","path":"$context.path","status":"$context.status","protocol":"$context.protocol","responseLength":"$context.responseLength","domainName":"$context.domainName","accountId":"$context.accountId"}`),
-				},
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/accessLogSettings/destinationArn"),
-					Value: aws.String(generateLogGroup(*accountId, *region, restApiId, *stage.StageName)),
-				},
-			},
-		})
+func resourceApiGatewayIntegrationUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	os, ns := restApiIdsChange(d)
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
 	}
-	return nil
+	if err := in.checkAccountLoggingRoles(sortedRestApiIds(ns)); err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The snapshots are saved along with the errors, so a failed apply
+	// continues from the stages already changed.
+	diags := deconfigureRestApis(d, meta, in, sortedRestApiIds(os.Difference(ns)))
+	// Every REST API is reconciled, a change of the stage filter or a drift
+	// dropped from state by Read can affect any of them.
+	diags = append(diags, configureRestApis(d, in, sortedRestApiIds(ns))...)
+	if d.HasChange("snapshot_store") {
+		diags = append(diags, storeStageStates(d, in)...)
+	}
+	if diags.HasError() {
+		return diags
+	}
+	return readApiGatewayIntegration(ctx, d, meta)
 }
 
-func resourceApiGatewayIntegrationUpdate(d *schema.ResourceData, meta interface{}) error {
-	conn := meta.(*conns.AWSClient).APIGatewayConn
-	old, new := d.GetChange("rest_api_ids")
-	if len(old.(*schema.Set).List()) > 0 {
-		for _, restApiId := range old.(*schema.Set).List() {
-			deconfigureRestApi(conn, d, restApiId.(string))
+// storeStageStates writes the snapshots taken before a snapshot store was
+// configured, or changed, to the snapshot store.
+func storeStageStates(d *schema.ResourceData, in *integration) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, state := range expandStageStates(d.Get("rest_api_states").([]interface{})) {
+		clients, err := in.targetClients(in.targetOf(state.restApiId))
+		if err == nil {
+			err = in.storeSnapshot(clients, state)
+		}
+		if err != nil {
+			diags = append(diags, restApiResult{restApiId: state.restApiId, stageName: state.stageName, err: err}.diagnostic("storing snapshot of"))
 		}
 	}
-	if len(new.(*schema.Set).List()) > 0 {
-		for _, restApiId := range new.(*schema.Set).List() {
-			configureRestApi(meta, d, restApiId.(string))
+	return diags
+}
+
+// configureRestApis configures the REST APIs in parallel and merges their
+// snapshots and created log groups back into the resource data, also when
+// some of them failed.
+func configureRestApis(d *schema.ResourceData, in *integration, restApiIds []string) diag.Diagnostics {
+	allStates := expandStageStates(d.Get("rest_api_states").([]interface{}))
+	results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+		return in.configureRestApi(restApiId, restApiStageStates(allStates, restApiId))
+	})
+
+	var diags diag.Diagnostics
+	managedLogGroups := d.Get("managed_log_groups").(*schema.Set)
+	for _, result := range results {
+		for _, name := range result.createdLogGroups {
+			managedLogGroups.Add(name)
+		}
+		if restoreDiag, ok := result.restoreDiagnostic(); ok {
+			diags = append(diags, restoreDiag)
+		}
+		if result.err != nil {
+			diags = append(diags, result.diagnostic("configuring"))
 		}
 	}
-	return nil
+
+	d.Set("rest_api_states", flattenStageStates(mergeStageStates(allStates, results)))
+	d.Set("managed_log_groups", managedLogGroups)
+	return diags
 }
 
-func deconfigureRestApi(conn *apigateway.APIGateway, d *schema.ResourceData, restApiId string) {
-	allStates := d.Get("rest_api_states").(map[string]interface{})
-	apiRes, _ := conn.GetStages(&apigateway.GetStagesInput{
-		RestApiId: &restApiId,
+// deconfigureRestApis restores the stages of the REST APIs in parallel, then
+// deletes the log groups created for the restored ones when delete_on_destroy
+// is set.
+func deconfigureRestApis(d *schema.ResourceData, meta interface{}, in *integration, restApiIds []string) diag.Diagnostics {
+	allStates := expandStageStates(d.Get("rest_api_states").([]interface{}))
+	results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+		return in.deconfigureRestApi(restApiId, restApiStageStates(allStates, restApiId))
 	})
-	for _, stage := range apiRes.Item {
-		idenifier := fmt.Sprintf("%v-%v", restApiId, *stage.StageName)
-		details := strings.Split(allStates[idenifier].(string), "!")
-		traceEnabled := details[0]
-		loggingLevel := details[1]
-		accessLogsFormat := details[2]
-		accessLogsDestinationArn := details[3]
-		patchOperation := []*apigateway.PatchOperation{
-			{
-				Op:    aws.String("replace"),
-				Path:  aws.String("/*/*/logging/loglevel"),
-				Value: aws.String(loggingLevel),
-			},
-			{
-				Op:    aws.String("replace"),
-				Path:  aws.String("/*/*/logging/dataTrace"),
-				Value: aws.String(traceEnabled),
-			},
+	d.Set("rest_api_states", flattenStageStates(mergeStageStates(allStates, results)))
+
+	var diags diag.Diagnostics
+	managedLogGroups := d.Get("managed_log_groups").(*schema.Set)
+	for _, result := range results {
+		if restoreDiag, ok := result.restoreDiagnostic(); ok {
+			diags = append(diags, restoreDiag)
 		}
-		if accessLogsFormat == "N0" {
-			patchOperation = append(patchOperation, &apigateway.PatchOperation{
+		if result.err != nil {
+			diags = append(diags, result.diagnostic("restoring"))
+			continue
+		}
+		if !in.logGroup.deleteOnDestroy {
+			continue
+		}
+
+		clients, err := in.targetClients(in.targetOf(result.restApiId))
+		if err == nil {
+			managedLogGroups, err = deleteManagedLogGroups(clients.logsConn, managedLogGroups, result.restApiId)
+		}
+		if err != nil {
+			diags = append(diags, diag.FromErr(err)...)
+		}
+	}
+
+	d.Set("managed_log_groups", managedLogGroups)
+	return diags
+}
+
+// restoreStagePatchOperations restores the snapshot of the stage. Wildcard
+// method settings created by the integration are removed, and snapshots of
+// method overrides deleted since are skipped.
+func restoreStagePatchOperations(stage *apigateway.Stage, state *StageState) []*apigateway.PatchOperation {
+	var patchOperations []*apigateway.PatchOperation
+	if state.wildcardAbsent {
+		if _, ok := stage.MethodSettings[WildcardMethodPath]; ok {
+			patchOperations = append(patchOperations, &apigateway.PatchOperation{
 				Op:   aws.String("remove"),
-				Path: aws.String("/accessLogSettings"),
+				Path: aws.String("/" + WildcardMethodPath),
 			})
-		} else {
-			patchOperation = append(patchOperation, []*apigateway.PatchOperation{
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/accessLogSettings/destinationArn"),
-					Value: aws.String(accessLogsDestinationArn),
-				},
-				{
-					Op:    aws.String("replace"),
-					Path:  aws.String("/accessLogSettings/format"),
-					Value: aws.String(accessLogsFormat),
-				},
-			}...)
 		}
-
-		conn.UpdateStage(&apigateway.UpdateStageInput{
-			RestApiId:       &restApiId,
-			StageName:       stage.StageName,
-			PatchOperations: patchOperation,
+	} else {
+		patchOperations = append(patchOperations, methodLoggingPatchOperations(WildcardMethodPath, state.loggingLevel, state.dataTraceEnabled)...)
+		if !state.metricsUnknown {
+			patchOperations = append(patchOperations, methodMetricsPatchOperation(WildcardMethodPath, state.metricsEnabled))
+		}
+	}
+	for _, settings := range state.methodSettings {
+		if _, ok := stage.MethodSettings[settings.methodPath]; !ok {
+			continue
+		}
+		patchOperations = append(patchOperations, methodLoggingPatchOperations(settings.methodPath, settings.loggingLevel, settings.dataTraceEnabled)...)
+		patchOperations = append(patchOperations, methodMetricsPatchOperation(settings.methodPath, settings.metricsEnabled))
+	}
+	if state.accessLogsDestinationArn == "" {
+		return append(patchOperations, &apigateway.PatchOperation{
+			Op:   aws.String("remove"),
+			Path: aws.String("/accessLogSettings"),
 		})
-		delete(allStates, idenifier)
 	}
-	d.Set("rest_api_states", allStates)
+	return append(patchOperations, []*apigateway.PatchOperation{
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String("/accessLogSettings/destinationArn"),
+			Value: aws.String(state.accessLogsDestinationArn),
+		},
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String("/accessLogSettings/format"),
+			Value: aws.String(state.accessLogsFormat),
+		},
+	}...)
 }
 
-func resourceApiGatewayIntegrationDelete(d *schema.ResourceData, meta interface{}) error {
-	conn := meta.(*conns.AWSClient).APIGatewayConn
-	allStates := d.Get("rest_api_states").(map[string]interface{})
-	for restApiId := range allStates {
-		deconfigureRestApi(conn, d, restApiId)
+func resourceApiGatewayIntegrationDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	restApiIds := d.Get("rest_api_ids").(*schema.Set).Union(d.Get("selected_rest_api_ids").(*schema.Set)).Union(apiIds(d.Get("api").(*schema.Set)))
+	for _, state := range expandStageStates(d.Get("rest_api_states").([]interface{})) {
+		restApiIds.Add(state.restApiId)
 	}
-	d.SetId("")
-	return nil
+
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	// The resource stays in state with the snapshots not restored yet, so
+	// the next destroy retries them.
+	diags := deconfigureRestApis(d, meta, in, sortedRestApiIds(restApiIds))
+	if !diags.HasError() {
+		if states := expandStageStates(d.Get("rest_api_states").([]interface{})); len(states) > 0 {
+			diags = append(diags, diag.Errorf("%d API Gateway stages changed since the integration configured them were not restored. "+
+				"Set restore_policy to %q to restore them, or remove the resource from the state to keep their current settings", len(states), restorePolicyForce)...)
+		}
+	}
+	return diags
 }
@@ -0,0 +1,107 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+const (
+	dataTraceAlways = "always"
+	dataTraceNever  = "never"
+	dataTraceTagged = "tagged"
+)
+
+func dataTrace_Values() []string {
+	return []string{dataTraceAlways, dataTraceNever, dataTraceTagged}
+}
+
+// dataTraceSettings decide which stages get data tracing, which writes full
+// request and response bodies to CloudWatch Logs. Tags of the REST API apply
+// to all its stages, tags of a stage override them.
+type dataTraceSettings struct {
+	mode          string
+	tags          map[string]string
+	sensitiveTags map[string]string
+}
+
+func expandDataTraceSettings(d resourceData) dataTraceSettings {
+	settings := dataTraceSettings{
+		mode:          d.Get("data_trace").(string),
+		tags:          make(map[string]string),
+		sensitiveTags: make(map[string]string),
+	}
+
+	for k, v := range d.Get("data_trace_tags").(map[string]interface{}) {
+		settings.tags[k] = v.(string)
+	}
+
+	for k, v := range d.Get("sensitive_tags").(map[string]interface{}) {
+		settings.sensitiveTags[k] = v.(string)
+	}
+
+	return settings
+}
+
+// needsTags reports whether the tags of the stages are needed to decide on
+// data tracing.
+func (s dataTraceSettings) needsTags() bool {
+	return s.mode == dataTraceTagged || len(s.sensitiveTags) > 0
+}
+
+// enabled reports whether data tracing is enabled on a stage with the tags.
+func (s dataTraceSettings) enabled(tags map[string]*string) bool {
+	switch s.mode {
+	case dataTraceNever:
+		return false
+	case dataTraceTagged:
+		_, ok := matchingTag(tags, s.tags)
+		return ok
+	default:
+		return true
+	}
+}
+
+// check returns an error when the configuration enables data tracing on a
+// stage tagged as sensitive.
+func (s dataTraceSettings) check(config stageConfiguration, tags map[string]*string) error {
+	if !config.dataTraceEnabled {
+		return nil
+	}
+	if key, ok := matchingTag(tags, s.sensitiveTags); ok {
+		return fmt.Errorf("data tracing would be enabled on a stage tagged as sensitive (%s), set data_trace to %q or %q to keep it off", key, dataTraceNever, dataTraceTagged)
+	}
+	return nil
+}
+
+// stageTags returns the tags of the stage merged over those of its REST API.
+func stageTags(restApiTags map[string]*string, stage *apigateway.Stage) map[string]*string {
+	tags := make(map[string]*string, len(restApiTags)+len(stage.Tags))
+	for k, v := range restApiTags {
+		tags[k] = v
+	}
+	for k, v := range stage.Tags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// matchingTag returns the first key, in sorted order, of the criteria the
+// tags match. An empty criterion value matches any value of the tag.
+func matchingTag(tags map[string]*string, criteria map[string]string) (string, bool) {
+	keys := make([]string, 0, len(criteria))
+	for k := range criteria {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		actual, ok := tags[k]
+		if ok && (criteria[k] == "" || aws.StringValue(actual) == criteria[k]) {
+			return k, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,109 @@
+package apigatewayintegration
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+func TestDataTraceSettingsEnabled(t *testing.T) {
+	restApiTags := aws.StringMap(map[string]string{"team": "payments"})
+
+	testCases := []struct {
+		name     string
+		settings dataTraceSettings
+		stage    *apigateway.Stage
+		expected bool
+	}{
+		{
+			name:     "always",
+			settings: dataTraceSettings{mode: dataTraceAlways},
+			stage:    &apigateway.Stage{},
+			expected: true,
+		},
+		{
+			name:     "never",
+			settings: dataTraceSettings{mode: dataTraceNever},
+			stage:    &apigateway.Stage{},
+			expected: false,
+		},
+		{
+			name:     "tagged stage",
+			settings: dataTraceSettings{mode: dataTraceTagged, tags: map[string]string{"noname:data-trace": "true"}},
+			stage:    &apigateway.Stage{Tags: aws.StringMap(map[string]string{"noname:data-trace": "true"})},
+			expected: true,
+		},
+		{
+			name:     "tagged REST API",
+			settings: dataTraceSettings{mode: dataTraceTagged, tags: map[string]string{"team": ""}},
+			stage:    &apigateway.Stage{},
+			expected: true,
+		},
+		{
+			name:     "stage tag overrides REST API tag",
+			settings: dataTraceSettings{mode: dataTraceTagged, tags: map[string]string{"team": "payments"}},
+			stage:    &apigateway.Stage{Tags: aws.StringMap(map[string]string{"team": "search"})},
+			expected: false,
+		},
+		{
+			name:     "untagged",
+			settings: dataTraceSettings{mode: dataTraceTagged, tags: map[string]string{"noname:data-trace": "true"}},
+			stage:    &apigateway.Stage{},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := tc.settings.enabled(stageTags(restApiTags, tc.stage)); actual != tc.expected {
+				t.Errorf("got %t, expected %t", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDataTraceSettingsCheck(t *testing.T) {
+	settings := dataTraceSettings{
+		mode:          dataTraceAlways,
+		sensitiveTags: map[string]string{"compliance": "pci", "hipaa": ""},
+	}
+
+	testCases := []struct {
+		name      string
+		config    stageConfiguration
+		tags      map[string]string
+		expectErr bool
+	}{
+		{
+			name:      "sensitive",
+			config:    stageConfiguration{dataTraceEnabled: true},
+			tags:      map[string]string{"hipaa": "yes"},
+			expectErr: true,
+		},
+		{
+			name:      "sensitive without data tracing",
+			config:    stageConfiguration{dataTraceEnabled: false},
+			tags:      map[string]string{"compliance": "pci"},
+			expectErr: false,
+		},
+		{
+			name:      "other tag value",
+			config:    stageConfiguration{dataTraceEnabled: true},
+			tags:      map[string]string{"compliance": "sox"},
+			expectErr: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := settings.check(tc.config, aws.StringMap(tc.tags))
+			if tc.expectErr && err == nil {
+				t.Error("expected error, got none")
+			}
+			if !tc.expectErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package apigatewayintegration
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+)
+
+func FindStagesByRestAPIID(conn *apigateway.APIGateway, restApiId string) ([]*apigateway.Stage, error) {
+	input := &apigateway.GetStagesInput{
+		RestApiId: aws.String(restApiId),
+	}
+
+	output, err := conn.GetStages(input)
+
+	if tfawserr.ErrCodeEquals(err, apigateway.ErrCodeNotFoundException) {
+		return nil, &resource.NotFoundError{
+			LastError:   err,
+			LastRequest: input,
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if output == nil {
+		return nil, tfresource.NewEmptyResultError(input)
+	}
+
+	return output.Item, nil
+}
+
+func FindRestApiByID(conn *apigateway.APIGateway, restApiId string) (*apigateway.RestApi, error) {
+	input := &apigateway.GetRestApiInput{
+		RestApiId: aws.String(restApiId),
+	}
+
+	output, err := conn.GetRestApi(input)
+
+	if tfawserr.ErrCodeEquals(err, apigateway.ErrCodeNotFoundException) {
+		return nil, &resource.NotFoundError{
+			LastError:   err,
+			LastRequest: input,
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if output == nil {
+		return nil, tfresource.NewEmptyResultError(input)
+	}
+
+	return output, nil
+}
+
+func FindLogGroupByName(conn *cloudwatchlogs.CloudWatchLogs, name string) (*cloudwatchlogs.LogGroup, error) {
+	input := &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(name),
+	}
+	var result *cloudwatchlogs.LogGroup
+
+	err := conn.DescribeLogGroupsPages(input, func(page *cloudwatchlogs.DescribeLogGroupsOutput, lastPage bool) bool {
+		if page == nil {
+			return !lastPage
+		}
+
+		for _, logGroup := range page.LogGroups {
+			if aws.StringValue(logGroup.LogGroupName) == name {
+				result = logGroup
+				return false
+			}
+		}
+
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, &resource.NotFoundError{
+			LastRequest: input,
+		}
+	}
+
+	return result, nil
+}
+
+func FindRestApis(conn *apigateway.APIGateway) ([]*apigateway.RestApi, error) {
+	input := &apigateway.GetRestApisInput{}
+	var result []*apigateway.RestApi
+
+	err := conn.GetRestApisPages(input, func(page *apigateway.GetRestApisOutput, lastPage bool) bool {
+		if page == nil {
+			return !lastPage
+		}
+
+		result = append(result, page.Items...)
+
+		return !lastPage
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
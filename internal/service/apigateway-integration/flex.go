@@ -0,0 +1,158 @@
+package apigatewayintegration
+
+import (
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+)
+
+func expandStageStates(tfList []interface{}) []StageState {
+	states := []StageState{}
+	for _, tfMapRaw := range tfList {
+		tfMap, ok := tfMapRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		states = append(states, StageState{
+			restApiId:                tfMap["rest_api_id"].(string),
+			stageName:                tfMap["stage"].(string),
+			loggingLevel:             tfMap["logging_level"].(string),
+			dataTraceEnabled:         tfMap["data_trace_enabled"].(bool),
+			metricsEnabled:           tfMap["metrics_enabled"].(bool),
+			accessLogsFormat:         tfMap["access_log_format"].(string),
+			accessLogsDestinationArn: tfMap["access_log_destination_arn"].(string),
+			region:                   tfMap["region"].(string),
+			accountId:                stringValue(tfMap["account_id"]),
+			assumeRoleArn:            stringValue(tfMap["assume_role_arn"]),
+			assumeRoleExternalId:     stringValue(tfMap["assume_role_external_id"]),
+			assumeRoleSessionName:    stringValue(tfMap["assume_role_session_name"]),
+			wildcardAbsent:           tfMap["wildcard_absent"] == true,
+			metricsUnknown:           tfMap["metrics_unknown"] == true,
+			methodSettings:           expandMethodSettingsStates(tfMap["method_settings"]),
+			applied:                  expandStageConfiguration(tfMap["applied_configuration"]),
+		})
+	}
+	return states
+}
+
+// stringValue returns the string of an attribute added to rest_api_states
+// since the state was written, or "".
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func expandMethodSettingsStates(v interface{}) []methodSettingsState {
+	tfList, _ := v.([]interface{})
+	states := []methodSettingsState{}
+	for _, tfMapRaw := range tfList {
+		tfMap, ok := tfMapRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		states = append(states, methodSettingsState{
+			methodPath:       tfMap["method_path"].(string),
+			loggingLevel:     tfMap["logging_level"].(string),
+			dataTraceEnabled: tfMap["data_trace_enabled"].(bool),
+			metricsEnabled:   tfMap["metrics_enabled"].(bool),
+		})
+	}
+	return states
+}
+
+func expandStageConfiguration(v interface{}) *stageConfiguration {
+	tfList, _ := v.([]interface{})
+	if len(tfList) == 0 || tfList[0] == nil {
+		return nil
+	}
+
+	tfMap := tfList[0].(map[string]interface{})
+	return &stageConfiguration{
+		loggingLevel:             tfMap["logging_level"].(string),
+		dataTraceEnabled:         tfMap["data_trace_enabled"].(bool),
+		accessLogsFormat:         tfMap["access_log_format"].(string),
+		accessLogsDestinationArn: tfMap["access_log_destination_arn"].(string),
+		forceMethodLogging:       tfMap["force_method_logging"].(bool),
+	}
+}
+
+func flattenStageStates(states []StageState) []interface{} {
+	tfList := []interface{}{}
+	for _, state := range states {
+		tfList = append(tfList, map[string]interface{}{
+			"rest_api_id":                state.restApiId,
+			"stage":                      state.stageName,
+			"logging_level":              state.loggingLevel,
+			"data_trace_enabled":         state.dataTraceEnabled,
+			"metrics_enabled":            state.metricsEnabled,
+			"access_log_format":          state.accessLogsFormat,
+			"access_log_destination_arn": state.accessLogsDestinationArn,
+			"region":                     state.region,
+			"account_id":                 state.accountId,
+			"assume_role_arn":            state.assumeRoleArn,
+			"assume_role_external_id":    state.assumeRoleExternalId,
+			"assume_role_session_name":   state.assumeRoleSessionName,
+			"wildcard_absent":            state.wildcardAbsent,
+			"metrics_unknown":            state.metricsUnknown,
+			"method_settings":            flattenMethodSettingsStates(state.methodSettings),
+			"applied_configuration":      flattenStageConfiguration(state.applied),
+		})
+	}
+	return tfList
+}
+
+func flattenMethodSettingsStates(states []methodSettingsState) []interface{} {
+	tfList := []interface{}{}
+	for _, state := range states {
+		tfList = append(tfList, map[string]interface{}{
+			"method_path":        state.methodPath,
+			"logging_level":      state.loggingLevel,
+			"data_trace_enabled": state.dataTraceEnabled,
+			"metrics_enabled":    state.metricsEnabled,
+		})
+	}
+	return tfList
+}
+
+func flattenStageConfiguration(config *stageConfiguration) []interface{} {
+	if config == nil {
+		return []interface{}{}
+	}
+
+	return []interface{}{map[string]interface{}{
+		"logging_level":              config.loggingLevel,
+		"data_trace_enabled":         config.dataTraceEnabled,
+		"access_log_format":          config.accessLogsFormat,
+		"access_log_destination_arn": config.accessLogsDestinationArn,
+		"force_method_logging":       config.forceMethodLogging,
+	}}
+}
+
+// expandApiTargets returns the target of the REST API of every api block.
+func expandApiTargets(tfSet *schema.Set) map[string]apiTarget {
+	targets := make(map[string]apiTarget)
+	for _, tfMapRaw := range tfSet.List() {
+		tfMap, ok := tfMapRaw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		target := apiTarget{
+			region: tfMap["region"].(string),
+		}
+		if v, ok := tfMap["assume_role"].([]interface{}); ok && len(v) > 0 && v[0] != nil {
+			target.assumeRole = conns.ExpandAssumeRole(v[0].(map[string]interface{}))
+		}
+		targets[tfMap["id"].(string)] = target
+	}
+	return targets
+}
+
+func apiIds(tfSet *schema.Set) *schema.Set {
+	ids := schema.NewSet(schema.HashString, nil)
+	for restApiId := range expandApiTargets(tfSet) {
+		ids.Add(restApiId)
+	}
+	return ids
+}
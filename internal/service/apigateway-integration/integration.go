@@ -0,0 +1,634 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/sts"
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+)
+
+const stageUpdateTimeout = 5 * time.Minute
+
+// resourceData is implemented by schema.ResourceData and schema.ResourceDiff,
+// so the integration can also be resolved at plan time.
+type resourceData interface {
+	Id() string
+	Get(key string) interface{}
+	GetOk(key string) (interface{}, bool)
+	GetChange(key string) (interface{}, interface{})
+}
+
+// integration holds what configuring and restoring the stages of REST APIs
+// needs. It is resolved once per apply and shared by the workers, which never
+// touch the resource data.
+type integration struct {
+	conn                     *apigateway.APIGateway
+	logsConn                 *cloudwatchlogs.CloudWatchLogs
+	ssmConn                  *ssm.SSM
+	session                  *session.Session
+	terraformVersion         string
+	accountId                string
+	region                   string
+	partition                string
+	targets                  map[string]apiTarget
+	parallelism              int
+	filter                   *stageFilter
+	accessLogsFormat         string
+	accessLogsDestinationArn string
+	forceMethodLogging       bool
+	dataTrace                dataTraceSettings
+	restorePolicy            string
+	id                       string
+	takeover                 bool
+	snapshots                snapshotStore
+	logGroup                 logGroupSettings
+	managedLogGroups         map[string]bool
+
+	mu          sync.Mutex
+	targetConns map[string]*targetConns
+}
+
+// apiTarget is where a REST API lives, its region and the role assumed to
+// reach its account. An empty region is the provider region, a nil
+// assumeRole the provider credentials.
+type apiTarget struct {
+	region     string
+	assumeRole *awsbase.AssumeRole
+}
+
+func (t apiTarget) key() string {
+	if t.assumeRole == nil {
+		return t.region
+	}
+	return strings.Join([]string{t.region, t.assumeRole.RoleARN, t.assumeRole.ExternalID, t.assumeRole.SessionName}, "|")
+}
+
+// targetConns are the clients of the REST APIs of a target.
+type targetConns struct {
+	conn       *apigateway.APIGateway
+	logsConn   *cloudwatchlogs.CloudWatchLogs
+	ssmConn    *ssm.SSM
+	accountId  string
+	region     string
+	partition  string
+	assumeRole *awsbase.AssumeRole
+}
+
+// setTarget records in the snapshot where it was taken, so the stage is
+// restored with the same credentials once its api block is gone.
+func (clients *targetConns) setTarget(state *StageState) {
+	state.region = clients.region
+	state.accountId = clients.accountId
+	if clients.assumeRole != nil {
+		state.assumeRoleArn = clients.assumeRole.RoleARN
+		state.assumeRoleExternalId = clients.assumeRole.ExternalID
+		state.assumeRoleSessionName = clients.assumeRole.SessionName
+	}
+}
+
+// target returns the target the snapshot was taken in.
+func (state StageState) target() apiTarget {
+	target := apiTarget{region: state.region}
+	if state.assumeRoleArn != "" {
+		target.assumeRole = &awsbase.AssumeRole{
+			RoleARN:     state.assumeRoleArn,
+			ExternalID:  state.assumeRoleExternalId,
+			SessionName: state.assumeRoleSessionName,
+		}
+	}
+	return target
+}
+
+func newIntegration(d resourceData, meta interface{}) (*integration, error) {
+	in, err := newClientIntegration(meta.(*conns.AWSClient))
+	if err != nil {
+		return nil, err
+	}
+
+	in.parallelism = d.Get("parallelism").(int)
+	in.filter = expandStageFilter(d)
+	in.accessLogsFormat = expandAccessLogFormat(d.Get("access_log_format").(string))
+	in.accessLogsDestinationArn = d.Get("access_log_destination_arn").(string)
+	in.forceMethodLogging = d.Get("force_method_logging").(bool)
+	in.dataTrace = expandDataTraceSettings(d)
+	in.restorePolicy = d.Get("restore_policy").(string)
+	in.id = d.Id()
+	in.takeover = d.Get("takeover").(bool)
+	in.logGroup = expandLogGroupSettings(d, meta)
+	for _, v := range d.Get("managed_log_groups").(*schema.Set).List() {
+		in.managedLogGroups[v.(string)] = true
+	}
+
+	in.snapshots, err = expandSnapshotStore(d.Get("snapshot_store").([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots remember the target of REST APIs no longer configured, the
+	// api blocks give the target of the others.
+	in.addStageStateTargets(expandStageStates(d.Get("rest_api_states").([]interface{})))
+	o, n := d.GetChange("api")
+	for _, apis := range []interface{}{o, n} {
+		for restApiId, target := range expandApiTargets(apis.(*schema.Set)) {
+			in.targets[restApiId] = target
+		}
+	}
+
+	return in, nil
+}
+
+// newClientIntegration returns an integration with the clients of the
+// provider and no configuration.
+func newClientIntegration(client *conns.AWSClient) (*integration, error) {
+	identity, err := client.STSConn.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("getting caller identity: %w", err)
+	}
+
+	return &integration{
+		conn:             client.APIGatewayConn,
+		logsConn:         client.LogsConn,
+		ssmConn:          client.SSMConn,
+		session:          client.Session,
+		terraformVersion: client.TerraformVersion,
+		accountId:        aws.StringValue(identity.Account),
+		region:           client.Region,
+		partition:        client.Partition,
+		targets:          make(map[string]apiTarget),
+		filter:           &stageFilter{},
+		managedLogGroups: make(map[string]bool),
+	}, nil
+}
+
+// addStageStateTargets makes the regions and roles remembered by the
+// snapshots the targets of their REST APIs.
+func (in *integration) addStageStateTargets(states []StageState) {
+	for _, state := range states {
+		if state.region != "" || state.assumeRoleArn != "" {
+			in.targets[state.restApiId] = state.target()
+		}
+	}
+}
+
+// targetOf returns the target of the REST API, the provider region and
+// credentials unless an api block or a snapshot names others.
+func (in *integration) targetOf(restApiId string) apiTarget {
+	target := in.targets[restApiId]
+	if target.region == "" {
+		target.region = in.region
+	}
+	return target
+}
+
+// targetClients returns the clients of the target, built from the provider
+// session the first time a target is used.
+func (in *integration) targetClients(target apiTarget) (*targetConns, error) {
+	if target.region == in.region && target.assumeRole == nil {
+		return &targetConns{conn: in.conn, logsConn: in.logsConn, ssmConn: in.ssmConn, accountId: in.accountId, region: in.region, partition: in.partition}, nil
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if clients, ok := in.targetConns[target.key()]; ok {
+		return clients, nil
+	}
+
+	sess, err := conns.NewSessionForRegion(in.session.Config.Copy(), target.region, in.terraformVersion)
+	if err != nil {
+		return nil, fmt.Errorf("creating AWS session (%s): %w", target.region, err)
+	}
+
+	accountId := in.accountId
+	if target.assumeRole != nil {
+		sess = assumeRoleSession(sess, target.assumeRole)
+		identity, err := sts.New(sess).GetCallerIdentity(&sts.GetCallerIdentityInput{})
+		if err != nil {
+			return nil, fmt.Errorf("getting caller identity of IAM Role (%s): %w", target.assumeRole.RoleARN, err)
+		}
+		accountId = aws.StringValue(identity.Account)
+	}
+
+	partition := in.partition
+	if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), target.region); ok {
+		partition = p.ID()
+	}
+
+	clients := &targetConns{
+		conn:       apigateway.New(sess),
+		logsConn:   cloudwatchlogs.New(sess),
+		ssmConn:    ssm.New(sess),
+		accountId:  accountId,
+		region:     target.region,
+		partition:  partition,
+		assumeRole: target.assumeRole,
+	}
+	if in.targetConns == nil {
+		in.targetConns = make(map[string]*targetConns)
+	}
+	in.targetConns[target.key()] = clients
+	return clients, nil
+}
+
+// assumeRoleSession returns a copy of the session using the credentials of
+// the assumed role.
+func assumeRoleSession(sess *session.Session, assumeRole *awsbase.AssumeRole) *session.Session {
+	creds := stscreds.NewCredentials(sess, assumeRole.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+		if assumeRole.ExternalID != "" {
+			p.ExternalID = aws.String(assumeRole.ExternalID)
+		}
+		if assumeRole.SessionName != "" {
+			p.RoleSessionName = assumeRole.SessionName
+		}
+		if assumeRole.Duration > 0 {
+			p.Duration = assumeRole.Duration
+		}
+		if assumeRole.Policy != "" {
+			p.Policy = aws.String(assumeRole.Policy)
+		}
+		for _, policyARN := range assumeRole.PolicyARNs {
+			p.PolicyArns = append(p.PolicyArns, &sts.PolicyDescriptorType{Arn: aws.String(policyARN)})
+		}
+		for k, v := range assumeRole.Tags {
+			p.Tags = append(p.Tags, &sts.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		p.TransitiveTagKeys = aws.StringSlice(assumeRole.TransitiveTagKeys)
+	})
+
+	return sess.Copy(&aws.Config{Credentials: creds})
+}
+
+// stageConfiguration returns the Noname logging configuration of a stage,
+// which logs to the configured access_log_destination_arn or to its own log
+// group. tags are the stage tags merged over those of its REST API.
+func (in *integration) stageConfiguration(clients *targetConns, restApiId string, stageName string, tags map[string]*string) stageConfiguration {
+	destinationArn := in.accessLogsDestinationArn
+	if destinationArn == "" {
+		destinationArn = generateLogGroup(clients.partition, clients.accountId, clients.region, restApiId, stageName)
+	}
+
+	return stageConfiguration{
+		loggingLevel:             NonameLoggingLevel,
+		dataTraceEnabled:         in.dataTrace.enabled(tags),
+		accessLogsFormat:         in.accessLogsFormat,
+		accessLogsDestinationArn: destinationArn,
+		forceMethodLogging:       in.forceMethodLogging,
+	}
+}
+
+// restApiResult is the outcome of configuring or restoring the stages of a
+// REST API. states are the remaining snapshots of the REST API, also when err
+// is set. stageName is the stage err happened on, if any.
+type restApiResult struct {
+	restApiId        string
+	stageName        string
+	states           []StageState
+	createdLogGroups []string
+	plannedChanges   []plannedChange
+	stageOutcomes    []stageOutcome
+	err              error
+}
+
+func (r restApiResult) diagnostic(action string) diag.Diagnostic {
+	summary := fmt.Sprintf("%s API Gateway REST API (%s)", action, r.restApiId)
+	if r.stageName != "" {
+		summary = fmt.Sprintf("%s stage (%s)", summary, r.stageName)
+	}
+	return diag.Diagnostic{
+		Severity: diag.Error,
+		Summary:  summary,
+		Detail:   r.err.Error(),
+	}
+}
+
+func (r restApiResult) failed(stageName string, err error) restApiResult {
+	r.stageName = stageName
+	r.err = err
+	return r
+}
+
+// forEachRestApi runs fn for every REST API on at most parallelism workers.
+// The results are in the order of restApiIds, whatever order the workers
+// finish in.
+func (in *integration) forEachRestApi(restApiIds []string, fn func(restApiId string) restApiResult) []restApiResult {
+	results := make([]restApiResult, len(restApiIds))
+	workers := in.parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = fn(restApiIds[i])
+			}
+		}()
+	}
+	for i := range restApiIds {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// findStages returns the stages of the REST API sorted by name, so every
+// apply walks them in the same order.
+func (in *integration) findStages(clients *targetConns, restApiId string) ([]*apigateway.Stage, error) {
+	outputRaw, err := tfresource.RetryWhenAWSErrCodeEquals(stageUpdateTimeout, func() (interface{}, error) {
+		return FindStagesByRestAPIID(clients.conn, restApiId)
+	}, apigateway.ErrCodeTooManyRequestsException)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stages := outputRaw.([]*apigateway.Stage)
+	sort.Slice(stages, func(i, j int) bool {
+		return aws.StringValue(stages[i].StageName) < aws.StringValue(stages[j].StageName)
+	})
+	return stages, nil
+}
+
+// restApiTags returns the tags of the REST API, or nil without reading them
+// when data tracing does not depend on tags.
+func (in *integration) restApiTags(clients *targetConns, restApiId string) (map[string]*string, error) {
+	if !in.dataTrace.needsTags() {
+		return nil, nil
+	}
+
+	outputRaw, err := tfresource.RetryWhenAWSErrCodeEquals(stageUpdateTimeout, func() (interface{}, error) {
+		return FindRestApiByID(clients.conn, restApiId)
+	}, apigateway.ErrCodeTooManyRequestsException)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return outputRaw.(*apigateway.RestApi).Tags, nil
+}
+
+func (in *integration) updateStage(clients *targetConns, restApiId string, stageName string, patchOperations []*apigateway.PatchOperation) error {
+	input := &apigateway.UpdateStageInput{
+		RestApiId:       aws.String(restApiId),
+		StageName:       aws.String(stageName),
+		PatchOperations: patchOperations,
+	}
+
+	_, err := tfresource.RetryWhenAWSErrCodeEquals(stageUpdateTimeout, func() (interface{}, error) {
+		return clients.conn.UpdateStage(input)
+	}, apigateway.ErrCodeTooManyRequestsException, apigateway.ErrCodeConflictException)
+
+	return err
+}
+
+// configureRestApi applies the Noname logging configuration to the stages of
+// the REST API matching the stage filter, and restores the ones that stopped
+// matching it. states are the snapshots of the REST API.
+func (in *integration) configureRestApi(restApiId string, states []StageState) restApiResult {
+	result := restApiResult{restApiId: restApiId, states: states}
+	clients, err := in.targetClients(in.targetOf(restApiId))
+	if err != nil {
+		return result.failed("", err)
+	}
+	stages, err := in.findStages(clients, restApiId)
+	if err != nil {
+		return result.failed("", fmt.Errorf("reading stages: %w", err))
+	}
+	restApiTags, err := in.restApiTags(clients, restApiId)
+	if err != nil {
+		return result.failed("", fmt.Errorf("reading tags: %w", err))
+	}
+
+	for _, stage := range stages {
+		stageName := aws.StringValue(stage.StageName)
+		if !in.filter.match(restApiId, stageName) {
+			if state := findStageState(result.states, restApiId, stageName); state != nil {
+				if err := in.restoreStage(clients, &result, stage, state, stageTags(restApiTags, stage)); err != nil {
+					return result.failed(stageName, fmt.Errorf("restoring stage: %w", err))
+				}
+			}
+			continue
+		}
+
+		// Claim the stage before snapshotting it, the settings of a stage
+		// owned by another integration are its configuration.
+		if err := in.claimStage(clients, restApiId, stage); err != nil {
+			return result.failed(stageName, err)
+		}
+
+		// Keep the snapshot taken when the stage was first configured, the
+		// current settings may be our own drifted configuration.
+		state := findStageState(result.states, restApiId, stageName)
+		if state == nil {
+			initial, err := in.initialStageState(clients, restApiId, stage)
+			if err != nil {
+				return result.failed(stageName, err)
+			}
+			result.states = append(result.states, *initial)
+			state = &result.states[len(result.states)-1]
+		} else {
+			overrides := len(state.methodSettings)
+			mergeMethodSettingsStates(state, stage)
+			if len(state.methodSettings) != overrides {
+				if err := in.storeSnapshot(clients, *state); err != nil {
+					return result.failed(stageName, err)
+				}
+			}
+		}
+
+		if in.accessLogsDestinationArn == "" {
+			name := logGroupName(restApiId, stageName)
+			created, err := ensureLogGroup(clients.logsConn, name, in.logGroup, in.managedLogGroups[name])
+			if err != nil {
+				return result.failed(stageName, fmt.Errorf("creating CloudWatch Logs Log Group (%s): %w", name, err))
+			}
+			if created {
+				result.createdLogGroups = append(result.createdLogGroups, name)
+			}
+		}
+
+		tags := stageTags(restApiTags, stage)
+		config := in.stageConfiguration(clients, restApiId, stageName, tags)
+		if err := in.dataTrace.check(config, tags); err != nil {
+			return result.failed(stageName, err)
+		}
+		if len(stageDrift(stage, config)) == 0 {
+			state.applied = &config
+			continue
+		}
+
+		if err := in.updateStage(clients, restApiId, stageName, configureStagePatchOperations(stage, config)); err != nil {
+			return result.failed(stageName, fmt.Errorf("updating stage: %w", err))
+		}
+		state.applied = &config
+	}
+
+	return result
+}
+
+// deconfigureRestApi restores the snapshots of the stages of the REST API,
+// skipping stages changed by someone else since. Snapshots of stages deleted
+// since the REST API was configured are dropped along with the restored ones.
+func (in *integration) deconfigureRestApi(restApiId string, states []StageState) restApiResult {
+	result := restApiResult{restApiId: restApiId, states: states}
+	clients, err := in.targetClients(in.targetOf(restApiId))
+	if err != nil {
+		return result.failed("", err)
+	}
+	stages, err := in.findStages(clients, restApiId)
+	if err != nil && !tfresource.NotFound(err) {
+		return result.failed("", fmt.Errorf("reading stages: %w", err))
+	}
+	// A REST API not found with the credentials of another account than the
+	// one of its snapshots may well still exist, its snapshots are kept.
+	if err != nil {
+		for _, state := range states {
+			if state.accountId != "" && state.accountId != clients.accountId {
+				return result.failed("", fmt.Errorf("REST API not found in account %s, its stages were configured in account %s: %w", clients.accountId, state.accountId, err))
+			}
+		}
+	}
+	var restApiTags map[string]*string
+	if len(stages) > 0 {
+		restApiTags, err = in.restApiTags(clients, restApiId)
+		if err != nil {
+			return result.failed("", fmt.Errorf("reading tags: %w", err))
+		}
+	}
+
+	for _, stage := range stages {
+		stageName := aws.StringValue(stage.StageName)
+		state := findStageState(result.states, restApiId, stageName)
+		if state == nil {
+			// Rebuild the snapshot lost with the Terraform state.
+			stored, err := in.storedSnapshot(clients, restApiId, stage)
+			if err != nil {
+				return result.failed(stageName, err)
+			}
+			if stored == nil {
+				continue
+			}
+			result.states = append(result.states, *stored)
+			state = &result.states[len(result.states)-1]
+		}
+
+		if err := in.restoreStage(clients, &result, stage, state, stageTags(restApiTags, stage)); err != nil {
+			return result.failed(stageName, fmt.Errorf("restoring stage: %w", err))
+		}
+	}
+
+	// The snapshots of skipped stages are kept for a later forced restore,
+	// the other ones left are of deleted stages.
+	kept := []StageState{}
+	for _, state := range result.states {
+		if result.skipped(state.stageName) {
+			kept = append(kept, state)
+			continue
+		}
+		if err := in.removeSnapshot(clients, restApiId, state.stageName); err != nil {
+			return result.failed(state.stageName, err)
+		}
+		result.stageOutcomes = append(result.stageOutcomes, stageOutcome{stageName: state.stageName, outcome: stageOutcomeMissing})
+	}
+	result.states = kept
+	return result
+}
+
+// initialStageState returns the snapshot of a stage configured for the first
+// time. A snapshot found in the snapshot store was taken before a lost
+// Terraform state and is kept, the current settings may be our own
+// configuration. Otherwise the current settings are snapshotted and stored.
+func (in *integration) initialStageState(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error) {
+	stored, err := in.storedSnapshot(clients, restApiId, stage)
+	if err != nil || stored != nil {
+		return stored, err
+	}
+
+	state := extractStageState(restApiId, stage)
+	clients.setTarget(&state)
+	if err := in.storeSnapshot(clients, state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (in *integration) storeSnapshot(clients *targetConns, state StageState) error {
+	if in.snapshots == nil {
+		return nil
+	}
+	return in.snapshots.put(clients, state)
+}
+
+func (in *integration) storedSnapshot(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error) {
+	if in.snapshots == nil {
+		return nil, nil
+	}
+	return in.snapshots.get(clients, restApiId, stage)
+}
+
+func (in *integration) removeSnapshot(clients *targetConns, restApiId string, stageName string) error {
+	if in.snapshots == nil {
+		return nil
+	}
+	return in.snapshots.remove(clients, restApiId, stageName)
+}
+
+// restApiStageStates returns the snapshots of the stages of the REST API.
+func restApiStageStates(states []StageState, restApiId string) []StageState {
+	restApiStates := []StageState{}
+	for _, state := range states {
+		if state.restApiId == restApiId {
+			restApiStates = append(restApiStates, state)
+		}
+	}
+	return restApiStates
+}
+
+// mergeStageStates replaces the snapshots of the REST APIs of the results
+// with the remaining ones.
+func mergeStageStates(states []StageState, results []restApiResult) []StageState {
+	processed := make(map[string]bool)
+	for _, result := range results {
+		processed[result.restApiId] = true
+	}
+
+	merged := []StageState{}
+	for _, state := range states {
+		if !processed[state.restApiId] {
+			merged = append(merged, state)
+		}
+	}
+	for _, result := range results {
+		merged = append(merged, result.states...)
+	}
+	return merged
+}
+
+func sortedRestApiIds(restApiIds *schema.Set) []string {
+	ids := []string{}
+	for _, v := range restApiIds.List() {
+		ids = append(ids, v.(string))
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,584 @@
+package apigatewayintegration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+)
+
+// fakeAPIGateway serves GetStages, UpdateStage, TagResource and
+// UntagResource for REST APIs with the same stages, returned out of order.
+// configuredStages have the configuration of testIntegration applied, owners
+// are the integrations owning stages. The first update of every REST API is
+// throttled, updates of deniedStage are denied. With notFound, no REST API
+// exists. patchPaths are the paths of the patch operations of the updates.
+type fakeAPIGateway struct {
+	stages           []string
+	configuredStages []string
+	owners           map[string]string
+	deniedStage      string
+	notFound         bool
+
+	mu         sync.Mutex
+	updates    map[string][]string
+	throttled  map[string]bool
+	tagged     []string
+	patchPaths []string
+}
+
+func (f *fakeAPIGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// /tags/<stage_arn>
+	if strings.HasPrefix(r.URL.Path, "/tags/") {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		stageName := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		f.tagged = append(f.tagged, r.Method+" "+stageName)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// /restapis/<rest_api_id>/stages[/<stage>]
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "restapis" || parts[2] != "stages" {
+		http.NotFound(w, r)
+		return
+	}
+	restApiId := parts[1]
+	if f.notFound {
+		w.Header().Set("X-Amzn-Errortype", "NotFoundException")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"Invalid API identifier specified"}`)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 3:
+		items := []map[string]interface{}{}
+		for _, stageName := range f.stages {
+			item := map[string]interface{}{"stageName": stageName}
+			if owner, ok := f.owners[stageName]; ok {
+				item["tags"] = map[string]string{IntegrationIdTagKey: owner}
+			}
+			for _, configured := range f.configuredStages {
+				if configured == stageName {
+					item["methodSettings"] = map[string]interface{}{
+						WildcardMethodPath: map[string]interface{}{"loggingLevel": NonameLoggingLevel, "dataTraceEnabled": true},
+					}
+					item["accessLogSettings"] = map[string]interface{}{
+						"format":         expandAccessLogFormat(accessLogFormatNonameJSONV1),
+						"destinationArn": testAccessLogsDestinationArn,
+					}
+				}
+			}
+			items = append(items, item)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"item": items})
+	case r.Method == http.MethodPatch && len(parts) == 4:
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !f.throttled[restApiId] {
+			f.throttled[restApiId] = true
+			w.Header().Set("X-Amzn-Errortype", apigateway.ErrCodeTooManyRequestsException)
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "Too Many Requests"})
+			return
+		}
+		if parts[3] == f.deniedStage {
+			w.Header().Set("X-Amzn-Errortype", "AccessDeniedException")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "User is not authorized to perform apigateway:PATCH"})
+			return
+		}
+		var input apigateway.UpdateStageInput
+		json.NewDecoder(r.Body).Decode(&input)
+		for _, op := range input.PatchOperations {
+			f.patchPaths = append(f.patchPaths, aws.StringValue(op.Path))
+		}
+		f.updates[restApiId] = append(f.updates[restApiId], parts[3])
+		json.NewEncoder(w).Encode(map[string]interface{}{"stageName": parts[3]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+const testAccessLogsDestinationArn = "arn:aws:logs:us-east-1:123456789012:log-group:access" //lintignore:AWSAT003,AWSAT005
+
+func testIntegration(t *testing.T, fake *fakeAPIGateway) *integration {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	return &integration{
+		conn:                     apigateway.New(sess),
+		parallelism:              3,
+		filter:                   &stageFilter{},
+		accessLogsFormat:         expandAccessLogFormat(accessLogFormatNonameJSONV1),
+		dataTrace:                dataTraceSettings{mode: dataTraceAlways},
+		accessLogsDestinationArn: testAccessLogsDestinationArn,
+	}
+}
+
+func TestIntegrationConfigureRestApi_ordering(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:    []string{"prod", "beta", "dev"},
+		updates:   make(map[string][]string),
+		throttled: make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+
+	restApiIds := []string{"a1b2c3d4e5", "f6g7h8i9j0", "k1l2m3n4o5", "p6q7r8s9t0", "u1v2w3x4y5"}
+	results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+		return in.configureRestApi(restApiId, []StageState{})
+	})
+
+	expectedStages := []string{"beta", "dev", "prod"}
+	for i, result := range results {
+		if result.err != nil {
+			t.Fatalf("configuring REST API (%s): %s", result.restApiId, result.err)
+		}
+		if result.restApiId != restApiIds[i] {
+			t.Errorf("result %d is for REST API %s, expected %s", i, result.restApiId, restApiIds[i])
+		}
+
+		var stageNames []string
+		for _, state := range result.states {
+			stageNames = append(stageNames, state.stageName)
+			if state.applied == nil || state.applied.accessLogsDestinationArn != testAccessLogsDestinationArn {
+				t.Errorf("REST API (%s) stage (%s) applied configuration: got %#v", result.restApiId, state.stageName, state.applied)
+			}
+		}
+		if !reflect.DeepEqual(stageNames, expectedStages) {
+			t.Errorf("REST API (%s) snapshots: got %v, expected %v", result.restApiId, stageNames, expectedStages)
+		}
+		if updates := fake.updates[result.restApiId]; !reflect.DeepEqual(updates, expectedStages) {
+			t.Errorf("REST API (%s) stage updates: got %v, expected %v", result.restApiId, updates, expectedStages)
+		}
+	}
+}
+
+func TestIntegrationConfigureRestApi_partialFailure(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:      []string{"prod", "beta", "dev"},
+		deniedStage: "dev",
+		updates:     make(map[string][]string),
+		throttled:   map[string]bool{"a1b2c3d4e5": true},
+	}
+	in := testIntegration(t, fake)
+
+	result := in.configureRestApi("a1b2c3d4e5", []StageState{})
+	if result.err == nil {
+		t.Fatal("expected error, got none")
+	}
+
+	if summary, expected := result.diagnostic("configuring").Summary, "configuring API Gateway REST API (a1b2c3d4e5) stage (dev)"; summary != expected {
+		t.Errorf("diagnostic summary: got %q, expected %q", summary, expected)
+	}
+
+	// The snapshots of the changed stage and of the failed one are kept, the
+	// stages after it are untouched.
+	var stageNames []string
+	for _, state := range result.states {
+		stageNames = append(stageNames, state.stageName)
+	}
+	if expected := []string{"beta", "dev"}; !reflect.DeepEqual(stageNames, expected) {
+		t.Errorf("snapshots: got %v, expected %v", stageNames, expected)
+	}
+}
+
+func TestIntegrationConfigureRestApi_storedSnapshot(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:           []string{"prod", "dev"},
+		configuredStages: []string{"prod"},
+		updates:          make(map[string][]string),
+		throttled:        make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+	in.snapshots = fileSnapshotStore{directory: t.TempDir()}
+	clients, err := in.targetClients(in.targetOf("a1b2c3d4e5"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// prod was configured before the Terraform state was lost, its snapshot
+	// survived in the store.
+	stored := StageState{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "ERROR", region: "us-east-1"} //lintignore:AWSAT003
+	if err := in.snapshots.put(clients, stored); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result := in.configureRestApi("a1b2c3d4e5", []StageState{})
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	loggingLevels := make(map[string]string)
+	for _, state := range result.states {
+		loggingLevels[state.stageName] = state.loggingLevel
+	}
+	if expected := map[string]string{"dev": loggingLevelOff, "prod": "ERROR"}; !reflect.DeepEqual(loggingLevels, expected) {
+		t.Errorf("snapshot logging levels: got %v, expected %v", loggingLevels, expected)
+	}
+
+	// The snapshot of dev is stored too.
+	if state, err := in.snapshots.get(clients, "a1b2c3d4e5", &apigateway.Stage{StageName: aws.String("dev")}); err != nil || state == nil {
+		t.Errorf("expected a stored snapshot of dev, got %v, %v", state, err)
+	}
+}
+
+// A stage failing to be configured does not record the configuration as
+// applied, so a safe destroy restores it instead of skipping it.
+func TestIntegrationConfigureRestApi_updateFailure(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:      []string{"prod", "beta", "dev"},
+		deniedStage: "dev",
+		updates:     make(map[string][]string),
+		throttled:   map[string]bool{"a1b2c3d4e5": true},
+	}
+	in := testIntegration(t, fake)
+	in.restorePolicy = restorePolicySafe
+
+	result := in.configureRestApi("a1b2c3d4e5", []StageState{})
+	if result.err == nil {
+		t.Fatal("expected error, got none")
+	}
+	for _, state := range result.states {
+		if applied := state.applied != nil; applied != (state.stageName == "beta") {
+			t.Errorf("stage (%s) applied configuration: got %#v", state.stageName, state.applied)
+		}
+	}
+
+	fake.deniedStage = ""
+	fake.configuredStages = []string{"beta"}
+	result = in.deconfigureRestApi("a1b2c3d4e5", result.states)
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	var outcomes []string
+	for _, o := range result.stageOutcomes {
+		outcomes = append(outcomes, o.stageName+" "+o.outcome)
+	}
+	if expected := []string{"beta restored", "dev restored"}; !reflect.DeepEqual(outcomes, expected) {
+		t.Errorf("outcomes: got %v, expected %v", outcomes, expected)
+	}
+}
+
+func TestIntegrationDeconfigureRestApi_conflict(t *testing.T) {
+	states := []StageState{
+		{restApiId: "a1b2c3d4e5", stageName: "dev", loggingLevel: "ERROR"},
+		{restApiId: "a1b2c3d4e5", stageName: "old", loggingLevel: "ERROR"},
+		{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "ERROR"},
+	}
+
+	testCases := []struct {
+		restorePolicy    string
+		expectedOutcomes []string
+		expectedUpdates  []string
+		expectedStates   []string
+	}{
+		{
+			restorePolicy:    restorePolicySafe,
+			expectedOutcomes: []string{"dev skipped", "prod restored", "old missing"},
+			expectedUpdates:  []string{"prod"},
+			expectedStates:   []string{"dev"},
+		},
+		{
+			restorePolicy:    restorePolicyForce,
+			expectedOutcomes: []string{"dev restored", "prod restored", "old missing"},
+			expectedUpdates:  []string{"dev", "prod"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.restorePolicy, func(t *testing.T) {
+			// dev was changed by someone else since it was configured.
+			fake := &fakeAPIGateway{
+				stages:           []string{"prod", "dev"},
+				configuredStages: []string{"prod"},
+				updates:          make(map[string][]string),
+				throttled:        map[string]bool{"a1b2c3d4e5": true},
+			}
+			in := testIntegration(t, fake)
+			in.restorePolicy = tc.restorePolicy
+
+			result := in.deconfigureRestApi("a1b2c3d4e5", states)
+			if result.err != nil {
+				t.Fatalf("unexpected error: %s", result.err)
+			}
+
+			var outcomes []string
+			for _, o := range result.stageOutcomes {
+				outcomes = append(outcomes, o.stageName+" "+o.outcome)
+			}
+			if !reflect.DeepEqual(outcomes, tc.expectedOutcomes) {
+				t.Errorf("outcomes: got %v, expected %v", outcomes, tc.expectedOutcomes)
+			}
+			if updates := fake.updates["a1b2c3d4e5"]; !reflect.DeepEqual(updates, tc.expectedUpdates) {
+				t.Errorf("stage updates: got %v, expected %v", updates, tc.expectedUpdates)
+			}
+			var remaining []string
+			for _, state := range result.states {
+				remaining = append(remaining, state.stageName)
+			}
+			if !reflect.DeepEqual(remaining, tc.expectedStates) {
+				t.Errorf("remaining snapshots: got %v, expected %v", remaining, tc.expectedStates)
+			}
+			if _, ok := result.restoreDiagnostic(); !ok {
+				t.Error("expected a restore diagnostic")
+			}
+		})
+	}
+}
+
+// The configuration changes in the apply that removes the REST API, its
+// stages are compared with the configuration applied before.
+func TestIntegrationDeconfigureRestApi_configurationChange(t *testing.T) {
+	applied := &stageConfiguration{
+		loggingLevel:             NonameLoggingLevel,
+		dataTraceEnabled:         true,
+		accessLogsFormat:         expandAccessLogFormat(accessLogFormatNonameJSONV1),
+		accessLogsDestinationArn: testAccessLogsDestinationArn,
+	}
+
+	testCases := []struct {
+		name             string
+		applied          *stageConfiguration
+		expectedOutcomes []string
+	}{
+		{
+			name:             "applied configuration",
+			applied:          applied,
+			expectedOutcomes: []string{"prod restored"},
+		},
+		{
+			name:             "no applied configuration",
+			expectedOutcomes: []string{"prod skipped"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeAPIGateway{
+				stages:           []string{"prod"},
+				configuredStages: []string{"prod"},
+				updates:          make(map[string][]string),
+				throttled:        make(map[string]bool),
+			}
+			in := testIntegration(t, fake)
+			in.restorePolicy = restorePolicySafe
+			in.dataTrace = dataTraceSettings{mode: dataTraceNever}
+
+			states := []StageState{
+				{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "ERROR", applied: tc.applied},
+			}
+			result := in.deconfigureRestApi("a1b2c3d4e5", states)
+			if result.err != nil {
+				t.Fatalf("unexpected error: %s", result.err)
+			}
+
+			var outcomes []string
+			for _, o := range result.stageOutcomes {
+				outcomes = append(outcomes, o.stageName+" "+o.outcome)
+			}
+			if !reflect.DeepEqual(outcomes, tc.expectedOutcomes) {
+				t.Errorf("outcomes: got %v, expected %v", outcomes, tc.expectedOutcomes)
+			}
+		})
+	}
+}
+
+func TestIntegrationDeconfigureRestApi_notFound(t *testing.T) {
+	testCases := []struct {
+		name        string
+		accountId   string
+		expectError bool
+	}{
+		{
+			name:      "same account",
+			accountId: "123456789012",
+		},
+		{
+			name:        "other account",
+			accountId:   "210987654321",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			states := []StageState{
+				{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "ERROR", accountId: tc.accountId},
+			}
+			in := testIntegration(t, &fakeAPIGateway{notFound: true})
+			in.accountId = "123456789012"
+
+			result := in.deconfigureRestApi("a1b2c3d4e5", states)
+			if tc.expectError {
+				if result.err == nil {
+					t.Fatal("expected an error")
+				}
+				if !reflect.DeepEqual(result.states, states) {
+					t.Errorf("snapshots: got %v, expected %v", result.states, states)
+				}
+				return
+			}
+			if result.err != nil {
+				t.Fatalf("unexpected error: %s", result.err)
+			}
+			if len(result.states) != 0 {
+				t.Errorf("expected no remaining snapshots, got %v", result.states)
+			}
+		})
+	}
+}
+
+func TestIntegrationStageConfiguration_target(t *testing.T) {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+	}))
+	in := &integration{
+		session:   sess,
+		accountId: "123456789012",
+		region:    "us-east-1", //lintignore:AWSAT003
+		partition: "aws",
+		targets: map[string]apiTarget{
+			"f6g7h8i9j0": {region: "eu-west-1"},  //lintignore:AWSAT003
+			"k1l2m3n4o5": {region: "cn-north-1"}, //lintignore:AWSAT003
+		},
+	}
+
+	testCases := []struct {
+		restApiId string
+		expected  string
+	}{
+		{restApiId: "a1b2c3d4e5", expected: "arn:aws:logs:us-east-1:123456789012:log-group:API-Gateway-Execution-Logs_a1b2c3d4e5/prod"},     //lintignore:AWSAT003,AWSAT005
+		{restApiId: "f6g7h8i9j0", expected: "arn:aws:logs:eu-west-1:123456789012:log-group:API-Gateway-Execution-Logs_f6g7h8i9j0/prod"},     //lintignore:AWSAT003,AWSAT005
+		{restApiId: "k1l2m3n4o5", expected: "arn:aws-cn:logs:cn-north-1:123456789012:log-group:API-Gateway-Execution-Logs_k1l2m3n4o5/prod"}, //lintignore:AWSAT003,AWSAT005
+	}
+
+	for _, tc := range testCases {
+		clients, err := in.targetClients(in.targetOf(tc.restApiId))
+		if err != nil {
+			t.Fatalf("creating clients of REST API (%s): %s", tc.restApiId, err)
+		}
+		if actual := in.stageConfiguration(clients, tc.restApiId, "prod", nil).accessLogsDestinationArn; actual != tc.expected {
+			t.Errorf("REST API (%s) destination ARN: got %s, expected %s", tc.restApiId, actual, tc.expected)
+		}
+	}
+}
+
+// fakeSTS serves AssumeRole, and GetCallerIdentity reporting the account of
+// the assumed role for requests signed with its credentials.
+func fakeSTS(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	switch r.Form.Get("Action") {
+	case "AssumeRole":
+		fmt.Fprint(w, `<AssumeRoleResponse><AssumeRoleResult>
+<Credentials><AccessKeyId>ASSUMEDAKID</AccessKeyId><SecretAccessKey>SECRET</SecretAccessKey><SessionToken>TOKEN</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials>
+</AssumeRoleResult></AssumeRoleResponse>`)
+	case "GetCallerIdentity":
+		account := "123456789012"
+		if strings.Contains(r.Header.Get("Authorization"), "ASSUMEDAKID") {
+			account = "210987654321"
+		}
+		fmt.Fprintf(w, `<GetCallerIdentityResponse><GetCallerIdentityResult><Account>%s</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`, account)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestIntegrationTargetClients_assumeRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(fakeSTS))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	in := &integration{
+		session:   sess,
+		accountId: "123456789012",
+		region:    "us-east-1", //lintignore:AWSAT003
+		partition: "aws",
+		targets: map[string]apiTarget{
+			"f6g7h8i9j0": {
+				assumeRole: &awsbase.AssumeRole{
+					RoleARN:    "arn:aws:iam::210987654321:role/noname", //lintignore:AWSAT005
+					ExternalID: "noname",
+				},
+			},
+		},
+	}
+
+	clients, err := in.targetClients(in.targetOf("f6g7h8i9j0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "arn:aws:logs:us-east-1:210987654321:log-group:API-Gateway-Execution-Logs_f6g7h8i9j0/prod" //lintignore:AWSAT003,AWSAT005
+	if actual := in.stageConfiguration(clients, "f6g7h8i9j0", "prod", nil).accessLogsDestinationArn; actual != expected {
+		t.Errorf("destination ARN: got %s, expected %s", actual, expected)
+	}
+}
+
+func TestIntegrationTargetClients_stageState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(fakeSTS))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	in := &integration{
+		session:   sess,
+		accountId: "123456789012",
+		region:    "us-east-1", //lintignore:AWSAT003
+		partition: "aws",
+		targets:   make(map[string]apiTarget),
+	}
+
+	// The api block of f6g7h8i9j0 is gone, its snapshot gives the target.
+	in.addStageStateTargets([]StageState{{
+		restApiId:            "f6g7h8i9j0",
+		stageName:            "prod",
+		region:               "us-east-1", //lintignore:AWSAT003
+		accountId:            "210987654321",
+		assumeRoleArn:        "arn:aws:iam::210987654321:role/noname", //lintignore:AWSAT005
+		assumeRoleExternalId: "noname",
+	}})
+
+	clients, err := in.targetClients(in.targetOf("f6g7h8i9j0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if clients.accountId != "210987654321" {
+		t.Errorf("account: got %s, expected 210987654321", clients.accountId)
+	}
+
+	var state StageState
+	clients.setTarget(&state)
+	if state.assumeRoleArn != "arn:aws:iam::210987654321:role/noname" || state.assumeRoleExternalId != "noname" { //lintignore:AWSAT005
+		t.Errorf("snapshot target: got %#v", state)
+	}
+}
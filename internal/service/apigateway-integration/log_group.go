@@ -0,0 +1,216 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+)
+
+const logGroupNamePrefix = "API-Gateway-Execution-Logs_"
+
+// logGroupSettings are the settings of the log groups created by the integration.
+type logGroupSettings struct {
+	retentionInDays int
+	kmsKeyId        string
+	tags            tftags.KeyValueTags
+	deleteOnDestroy bool
+}
+
+func expandLogGroupSettings(d resourceData, meta interface{}) logGroupSettings {
+	defaultTagsConfig := meta.(*conns.AWSClient).DefaultTagsConfig
+	settings := logGroupSettings{
+		tags: defaultTagsConfig.MergeTags(tftags.New(map[string]interface{}{})),
+	}
+
+	if l, ok := d.Get("log_group").([]interface{}); ok && len(l) > 0 && l[0] != nil {
+		tfMap := l[0].(map[string]interface{})
+		settings.retentionInDays = tfMap["retention_in_days"].(int)
+		settings.kmsKeyId = tfMap["kms_key_id"].(string)
+		settings.tags = defaultTagsConfig.MergeTags(tftags.New(tfMap["tags"].(map[string]interface{})))
+		settings.deleteOnDestroy = tfMap["delete_on_destroy"].(bool)
+	}
+
+	return settings
+}
+
+func logGroupName(restApiId string, stageName string) string {
+	return fmt.Sprintf("%v%v/%v", logGroupNamePrefix, restApiId, stageName)
+}
+
+// logGroupNames returns the log groups of the provider account and region
+// the configured stages write to: their execution log groups and, when
+// access logs go to a CloudWatch Logs log group, their access log group.
+func (in *integration) logGroupNames(states []StageState) *schema.Set {
+	names := schema.NewSet(schema.HashString, nil)
+	for _, state := range states {
+		target := in.targetOf(state.restApiId)
+		if target.region != in.region || target.assumeRole != nil {
+			continue
+		}
+		names.Add(logGroupName(state.restApiId, state.stageName))
+	}
+
+	if names.Len() > 0 && in.accessLogsDestinationArn != "" {
+		if name, ok := logGroupNameFromArn(in.accessLogsDestinationArn); ok {
+			names.Add(name)
+		}
+	}
+
+	return names
+}
+
+// logGroupNameFromArn returns the name of the log group of a CloudWatch Logs
+// ARN, with or without the trailing ":*".
+func logGroupNameFromArn(s string) (string, bool) {
+	v, err := arn.Parse(s)
+	if err != nil || v.Service != cloudwatchlogs.ServiceName || !strings.HasPrefix(v.Resource, "log-group:") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(v.Resource, "log-group:"), ":*"), true
+}
+
+// ensureLogGroup creates the log group when it does not exist and reports
+// whether it did. The retention, KMS key and tags of managed log groups
+// follow the settings, log groups created outside of the integration are
+// left untouched.
+func ensureLogGroup(conn *cloudwatchlogs.CloudWatchLogs, name string, settings logGroupSettings, managed bool) (bool, error) {
+	logGroup, err := FindLogGroupByName(conn, name)
+
+	if err != nil && !tfresource.NotFound(err) {
+		return false, err
+	}
+
+	created := false
+	retentionInDays := 0
+	if logGroup == nil {
+		input := &cloudwatchlogs.CreateLogGroupInput{
+			LogGroupName: aws.String(name),
+		}
+		if settings.kmsKeyId != "" {
+			input.KmsKeyId = aws.String(settings.kmsKeyId)
+		}
+		if tags := settings.tags.IgnoreAWS(); len(tags) > 0 {
+			input.Tags = aws.StringMap(tags.Map())
+		}
+
+		log.Printf("[DEBUG] Creating CloudWatch Logs Log Group: %s", input)
+		if _, err := conn.CreateLogGroup(input); err != nil {
+			return false, err
+		}
+		created = true
+	} else if !managed {
+		return false, nil
+	} else {
+		retentionInDays = int(aws.Int64Value(logGroup.RetentionInDays))
+		if err := updateLogGroupKmsKey(conn, name, aws.StringValue(logGroup.KmsKeyId), settings.kmsKeyId); err != nil {
+			return false, err
+		}
+		if err := updateLogGroupTags(conn, name, settings.tags); err != nil {
+			return false, err
+		}
+	}
+
+	if retentionInDays == settings.retentionInDays {
+		return created, nil
+	}
+
+	if settings.retentionInDays == 0 {
+		_, err = conn.DeleteRetentionPolicy(&cloudwatchlogs.DeleteRetentionPolicyInput{
+			LogGroupName: aws.String(name),
+		})
+	} else {
+		_, err = conn.PutRetentionPolicy(&cloudwatchlogs.PutRetentionPolicyInput{
+			LogGroupName:    aws.String(name),
+			RetentionInDays: aws.Int64(int64(settings.retentionInDays)),
+		})
+	}
+
+	return created, err
+}
+
+func updateLogGroupKmsKey(conn *cloudwatchlogs.CloudWatchLogs, name string, oldKmsKeyId string, newKmsKeyId string) error {
+	if oldKmsKeyId == newKmsKeyId {
+		return nil
+	}
+
+	var err error
+	if newKmsKeyId == "" {
+		_, err = conn.DisassociateKmsKey(&cloudwatchlogs.DisassociateKmsKeyInput{
+			LogGroupName: aws.String(name),
+		})
+	} else {
+		_, err = conn.AssociateKmsKey(&cloudwatchlogs.AssociateKmsKeyInput{
+			LogGroupName: aws.String(name),
+			KmsKeyId:     aws.String(newKmsKeyId),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("updating CloudWatch Logs Log Group (%s) KMS key: %w", name, err)
+	}
+	return nil
+}
+
+func updateLogGroupTags(conn *cloudwatchlogs.CloudWatchLogs, name string, tags tftags.KeyValueTags) error {
+	output, err := conn.ListTagsLogGroup(&cloudwatchlogs.ListTagsLogGroupInput{
+		LogGroupName: aws.String(name),
+	})
+	if err != nil {
+		return fmt.Errorf("listing CloudWatch Logs Log Group (%s) tags: %w", name, err)
+	}
+
+	oldTags := tftags.New(output.Tags).IgnoreAWS()
+	newTags := tags.IgnoreAWS()
+	if removed := oldTags.Removed(newTags); len(removed) > 0 {
+		_, err := conn.UntagLogGroup(&cloudwatchlogs.UntagLogGroupInput{
+			LogGroupName: aws.String(name),
+			Tags:         aws.StringSlice(removed.Keys()),
+		})
+		if err != nil {
+			return fmt.Errorf("untagging CloudWatch Logs Log Group (%s): %w", name, err)
+		}
+	}
+	if updated := oldTags.Updated(newTags); len(updated) > 0 {
+		_, err := conn.TagLogGroup(&cloudwatchlogs.TagLogGroupInput{
+			LogGroupName: aws.String(name),
+			Tags:         aws.StringMap(updated.Map()),
+		})
+		if err != nil {
+			return fmt.Errorf("tagging CloudWatch Logs Log Group (%s): %w", name, err)
+		}
+	}
+	return nil
+}
+
+// deleteManagedLogGroups deletes the log groups created by the integration
+// for the REST API and returns the remaining managed log groups.
+func deleteManagedLogGroups(conn *cloudwatchlogs.CloudWatchLogs, managedLogGroups *schema.Set, restApiId string) (*schema.Set, error) {
+	prefix := fmt.Sprintf("%v%v/", logGroupNamePrefix, restApiId)
+	for _, v := range managedLogGroups.List() {
+		name := v.(string)
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		log.Printf("[DEBUG] Deleting CloudWatch Logs Log Group: %s", name)
+		_, err := conn.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{
+			LogGroupName: aws.String(name),
+		})
+
+		if err != nil && !tfawserr.ErrCodeEquals(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+			return managedLogGroups, fmt.Errorf("deleting CloudWatch Logs Log Group (%s): %w", name, err)
+		}
+
+		managedLogGroups.Remove(name)
+	}
+
+	return managedLogGroups, nil
+}
@@ -0,0 +1,137 @@
+package apigatewayintegration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+)
+
+// fakeLogs serves a single existing log group with a KMS key and tags, and
+// records the operations changing it.
+type fakeLogs struct {
+	logGroup map[string]interface{}
+	tags     map[string]string
+
+	calls []string
+}
+
+func (f *fakeLogs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	operation := r.Header.Get("X-Amz-Target")
+	operation = operation[strings.Index(operation, ".")+1:]
+
+	var input map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&input)
+
+	switch operation {
+	case "DescribeLogGroups":
+		json.NewEncoder(w).Encode(map[string]interface{}{"logGroups": []interface{}{f.logGroup}})
+		return
+	case "ListTagsLogGroup":
+		json.NewEncoder(w).Encode(map[string]interface{}{"tags": f.tags})
+		return
+	case "AssociateKmsKey":
+		f.calls = append(f.calls, operation+" "+input["kmsKeyId"].(string))
+	case "TagLogGroup":
+		for k, v := range input["tags"].(map[string]interface{}) {
+			f.calls = append(f.calls, operation+" "+k+"="+v.(string))
+		}
+	case "UntagLogGroup":
+		for _, k := range input["tags"].([]interface{}) {
+			f.calls = append(f.calls, operation+" "+k.(string))
+		}
+	default:
+		f.calls = append(f.calls, operation)
+	}
+	w.Write([]byte("{}"))
+}
+
+func TestEnsureLogGroup_managedSettings(t *testing.T) {
+	name := logGroupName("a1b2c3d4e5", "prod")
+	newKmsKeyId := "arn:aws:kms:us-east-1:123456789012:key/new" //lintignore:AWSAT003,AWSAT005
+
+	testCases := []struct {
+		name     string
+		managed  bool
+		settings logGroupSettings
+		expected []string
+	}{
+		{
+			name:    "unchanged",
+			managed: true,
+			settings: logGroupSettings{
+				retentionInDays: 30,
+				kmsKeyId:        "arn:aws:kms:us-east-1:123456789012:key/old", //lintignore:AWSAT003,AWSAT005
+				tags:            tftags.New(map[string]string{"team": "api"}),
+			},
+			expected: nil,
+		},
+		{
+			name:    "changed",
+			managed: true,
+			settings: logGroupSettings{
+				retentionInDays: 30,
+				kmsKeyId:        newKmsKeyId,
+				tags:            tftags.New(map[string]string{"owner": "noname"}),
+			},
+			expected: []string{"AssociateKmsKey " + newKmsKeyId, "UntagLogGroup team", "TagLogGroup owner=noname"},
+		},
+		{
+			name:    "removed",
+			managed: true,
+			settings: logGroupSettings{
+				retentionInDays: 30,
+				tags:            tftags.New(map[string]string{"team": "api"}),
+			},
+			expected: []string{"DisassociateKmsKey"},
+		},
+		{
+			name:    "not managed",
+			managed: false,
+			settings: logGroupSettings{
+				kmsKeyId: newKmsKeyId,
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeLogs{
+				logGroup: map[string]interface{}{
+					"logGroupName":    name,
+					"retentionInDays": 30,
+					"kmsKeyId":        "arn:aws:kms:us-east-1:123456789012:key/old", //lintignore:AWSAT003,AWSAT005
+				},
+				tags: map[string]string{"team": "api"},
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			conn := cloudwatchlogs.New(session.Must(session.NewSession(&aws.Config{
+				Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+				Endpoint:    aws.String(server.URL),
+				Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+				MaxRetries:  aws.Int(0),
+			})))
+
+			created, err := ensureLogGroup(conn, name, tc.settings, tc.managed)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if created {
+				t.Error("existing log group reported as created")
+			}
+			if !reflect.DeepEqual(fake.calls, tc.expected) {
+				t.Errorf("got %v, expected %v", fake.calls, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+// WildcardMethodPath is the method path of the settings of all methods of a
+// stage.
+const WildcardMethodPath = "*/*"
+
+const (
+	loggingLevelOff          = "OFF"
+	methodSettingsPathFormat = "/%s/%s"
+)
+
+// methodSettingsState is the snapshot of the logging settings of a method
+// override, keyed by its "<resource_path>/<http_method>" method path.
+type methodSettingsState struct {
+	methodPath       string
+	loggingLevel     string
+	dataTraceEnabled bool
+	metricsEnabled   bool
+}
+
+// methodLoggingLevel returns the logging level of method settings, which is
+// OFF when they do not exist or do not set it.
+func methodLoggingLevel(settings *apigateway.MethodSetting) string {
+	if settings == nil || aws.StringValue(settings.LoggingLevel) == "" {
+		return loggingLevelOff
+	}
+	return aws.StringValue(settings.LoggingLevel)
+}
+
+// methodOverridePaths returns the sorted method paths of the method
+// overrides of the stage, every method settings key but the wildcard.
+func methodOverridePaths(stage *apigateway.Stage) []string {
+	methodPaths := []string{}
+	for methodPath := range stage.MethodSettings {
+		if methodPath != WildcardMethodPath {
+			methodPaths = append(methodPaths, methodPath)
+		}
+	}
+	sort.Strings(methodPaths)
+	return methodPaths
+}
+
+func extractMethodSettingsState(methodPath string, settings *apigateway.MethodSetting) methodSettingsState {
+	return methodSettingsState{
+		methodPath:       methodPath,
+		loggingLevel:     methodLoggingLevel(settings),
+		dataTraceEnabled: aws.BoolValue(settings.DataTraceEnabled),
+		metricsEnabled:   aws.BoolValue(settings.MetricsEnabled),
+	}
+}
+
+// mergeMethodSettingsStates adds the method overrides of the stage missing
+// from the snapshot, such as overrides created after it was taken. They were
+// never changed by the integration, so their current settings are the ones
+// to restore.
+func mergeMethodSettingsStates(state *StageState, stage *apigateway.Stage) {
+	for _, methodPath := range methodOverridePaths(stage) {
+		if findMethodSettingsState(state.methodSettings, methodPath) == nil {
+			state.methodSettings = append(state.methodSettings, extractMethodSettingsState(methodPath, stage.MethodSettings[methodPath]))
+		}
+	}
+}
+
+func findMethodSettingsState(states []methodSettingsState, methodPath string) *methodSettingsState {
+	for i := range states {
+		if states[i].methodPath == methodPath {
+			return &states[i]
+		}
+	}
+	return nil
+}
+
+func methodLoggingPatchOperations(methodPath string, loggingLevel string, dataTraceEnabled bool) []*apigateway.PatchOperation {
+	return []*apigateway.PatchOperation{
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String(fmt.Sprintf(methodSettingsPathFormat, methodPath, "logging/loglevel")),
+			Value: aws.String(loggingLevel),
+		},
+		{
+			Op:    aws.String("replace"),
+			Path:  aws.String(fmt.Sprintf(methodSettingsPathFormat, methodPath, "logging/dataTrace")),
+			Value: aws.String(strconv.FormatBool(dataTraceEnabled)),
+		},
+	}
+}
+
+func methodMetricsPatchOperation(methodPath string, metricsEnabled bool) *apigateway.PatchOperation {
+	return &apigateway.PatchOperation{
+		Op:    aws.String("replace"),
+		Path:  aws.String(fmt.Sprintf(methodSettingsPathFormat, methodPath, "metrics/enabled")),
+		Value: aws.String(strconv.FormatBool(metricsEnabled)),
+	}
+}
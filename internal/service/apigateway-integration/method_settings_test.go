@@ -0,0 +1,105 @@
+package apigatewayintegration
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+func TestExtractStageState_noWildcard(t *testing.T) {
+	stage := &apigateway.Stage{
+		StageName: aws.String("prod"),
+		MethodSettings: map[string]*apigateway.MethodSetting{
+			"pets/GET": {
+				LoggingLevel:     aws.String("OFF"),
+				DataTraceEnabled: aws.Bool(false),
+				MetricsEnabled:   aws.Bool(true),
+			},
+		},
+	}
+
+	expected := StageState{
+		restApiId:      "a1b2c3d4e5",
+		stageName:      "prod",
+		loggingLevel:   "OFF",
+		wildcardAbsent: true,
+		methodSettings: []methodSettingsState{
+			{
+				methodPath:     "pets/GET",
+				loggingLevel:   "OFF",
+				metricsEnabled: true,
+			},
+		},
+	}
+
+	if actual := extractStageState("a1b2c3d4e5", stage); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Got:\n\n%#v\n\nExpected:\n\n%#v", actual, expected)
+	}
+}
+
+func TestRestoreStagePatchOperations(t *testing.T) {
+	stage := &apigateway.Stage{
+		StageName: aws.String("prod"),
+		MethodSettings: map[string]*apigateway.MethodSetting{
+			"*/*":      {LoggingLevel: aws.String("INFO")},
+			"pets/GET": {LoggingLevel: aws.String("INFO")},
+		},
+	}
+	state := &StageState{
+		restApiId:      "a1b2c3d4e5",
+		stageName:      "prod",
+		loggingLevel:   "OFF",
+		wildcardAbsent: true,
+		methodSettings: []methodSettingsState{
+			{methodPath: "pets/GET", loggingLevel: "ERROR", metricsEnabled: true},
+			{methodPath: "pets/POST", loggingLevel: "OFF"},
+		},
+	}
+
+	var actual []string
+	for _, op := range restoreStagePatchOperations(stage, state) {
+		actual = append(actual, aws.StringValue(op.Op)+" "+aws.StringValue(op.Path)+" "+aws.StringValue(op.Value))
+	}
+
+	expected := []string{
+		"remove /*/* ",
+		"replace /pets/GET/logging/loglevel ERROR",
+		"replace /pets/GET/logging/dataTrace false",
+		"replace /pets/GET/metrics/enabled true",
+		"remove /accessLogSettings ",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Got:\n\n%#v\n\nExpected:\n\n%#v", actual, expected)
+	}
+}
+
+func TestStageDrift_forceMethodLogging(t *testing.T) {
+	stage := &apigateway.Stage{
+		MethodSettings: map[string]*apigateway.MethodSetting{
+			"*/*":      {LoggingLevel: aws.String("INFO"), DataTraceEnabled: aws.Bool(true)},
+			"pets/GET": {LoggingLevel: aws.String("OFF"), DataTraceEnabled: aws.Bool(true)},
+		},
+		AccessLogSettings: &apigateway.AccessLogSettings{
+			Format:         aws.String("$context.requestId"),
+			DestinationArn: aws.String("arn:aws:logs:us-east-1:123456789012:log-group:access"), //lintignore:AWSAT003,AWSAT005
+		},
+	}
+	config := stageConfiguration{
+		loggingLevel:             "INFO",
+		dataTraceEnabled:         true,
+		accessLogsFormat:         "$context.requestId",
+		accessLogsDestinationArn: "arn:aws:logs:us-east-1:123456789012:log-group:access", //lintignore:AWSAT003,AWSAT005
+	}
+
+	if drift := stageDrift(stage, config); len(drift) != 0 {
+		t.Fatalf("expected no drift without force_method_logging, got %v", drift)
+	}
+
+	config.forceMethodLogging = true
+	if drift, expected := stageDrift(stage, config), []string{"pets/GET.loggingLevel"}; !reflect.DeepEqual(drift, expected) {
+		t.Fatalf("Got %v, expected %v", drift, expected)
+	}
+}
@@ -0,0 +1,90 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+)
+
+// IntegrationIdTagKey tags the stages with the ID of the integration owning
+// them, so two integrations never snapshot each other's configuration.
+const IntegrationIdTagKey = "noname:integration-id"
+
+// stageOwner returns the ID of the integration owning the stage, or "" when
+// no integration does.
+func stageOwner(stage *apigateway.Stage) string {
+	return aws.StringValue(stage.Tags[IntegrationIdTagKey])
+}
+
+// ownedByOther reports whether another integration owns the stage.
+func (in *integration) ownedByOther(stage *apigateway.Stage) bool {
+	owner := stageOwner(stage)
+	return owner != "" && owner != in.id
+}
+
+// checkStageOwner returns an error naming the owner when another integration
+// owns the stage, unless takeover is set.
+func (in *integration) checkStageOwner(stage *apigateway.Stage) error {
+	if in.ownedByOther(stage) && !in.takeover {
+		return fmt.Errorf("stage is owned by noname_api_gateway_integration (%s), set takeover to take it over", stageOwner(stage))
+	}
+	return nil
+}
+
+// claimStage tags the stage with the ID of the integration, taking it over
+// from its current owner when takeover is set.
+func (in *integration) claimStage(clients *targetConns, restApiId string, stage *apigateway.Stage) error {
+	if err := in.checkStageOwner(stage); err != nil {
+		return err
+	}
+	if stageOwner(stage) == in.id {
+		return nil
+	}
+
+	resourceArn := stageArn(clients, restApiId, aws.StringValue(stage.StageName))
+	_, err := clients.conn.TagResource(&apigateway.TagResourceInput{
+		ResourceArn: aws.String(resourceArn),
+		Tags:        aws.StringMap(map[string]string{IntegrationIdTagKey: in.id}),
+	})
+	if err != nil {
+		return fmt.Errorf("tagging API Gateway Stage (%s): %w", resourceArn, err)
+	}
+	return nil
+}
+
+// releaseStage removes the ownership tag of the integration from the stage.
+func (in *integration) releaseStage(clients *targetConns, restApiId string, stage *apigateway.Stage) error {
+	if stageOwner(stage) == "" || (in.ownedByOther(stage) && !in.takeover) {
+		return nil
+	}
+
+	resourceArn := stageArn(clients, restApiId, aws.StringValue(stage.StageName))
+	_, err := clients.conn.UntagResource(&apigateway.UntagResourceInput{
+		ResourceArn: aws.String(resourceArn),
+		TagKeys:     aws.StringSlice([]string{IntegrationIdTagKey}),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, apigateway.ErrCodeNotFoundException) {
+		return fmt.Errorf("untagging API Gateway Stage (%s): %w", resourceArn, err)
+	}
+	return nil
+}
+
+// importIntegrationId returns the ID of the integration owning all the
+// stages, so an imported integration keeps owning them, or "" when they have
+// no single owner.
+func importIntegrationId(stages []*apigateway.Stage) string {
+	id := ""
+	for _, stage := range stages {
+		owner := stageOwner(stage)
+		if owner == "" {
+			continue
+		}
+		if id != "" && owner != id {
+			return ""
+		}
+		id = owner
+	}
+	return id
+}
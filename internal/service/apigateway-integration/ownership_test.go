@@ -0,0 +1,92 @@
+package apigatewayintegration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntegrationConfigureRestApi_ownership(t *testing.T) {
+	testCases := []struct {
+		name           string
+		takeover       bool
+		expectErr      bool
+		expectedTagged []string
+	}{
+		{
+			name:           "conflict",
+			expectErr:      true,
+			expectedTagged: []string{"PUT dev"},
+		},
+		{
+			name:           "takeover",
+			takeover:       true,
+			expectedTagged: []string{"PUT dev", "PUT prod"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// qa is already owned by this integration.
+			fake := &fakeAPIGateway{
+				stages:    []string{"prod", "dev", "qa"},
+				owners:    map[string]string{"prod": "other-integration", "qa": "this-integration"},
+				updates:   make(map[string][]string),
+				throttled: map[string]bool{"a1b2c3d4e5": true},
+			}
+			in := testIntegration(t, fake)
+			in.id = "this-integration"
+			in.takeover = tc.takeover
+
+			result := in.configureRestApi("a1b2c3d4e5", []StageState{})
+			if tc.expectErr {
+				if result.err == nil || result.stageName != "prod" || !strings.Contains(result.err.Error(), "other-integration") {
+					t.Errorf("expected an error naming the owner of stage prod, got %v on stage %q", result.err, result.stageName)
+				}
+				for _, state := range result.states {
+					if state.stageName == "prod" {
+						t.Error("expected no snapshot of stage prod")
+					}
+				}
+			} else if result.err != nil {
+				t.Fatalf("unexpected error: %s", result.err)
+			}
+
+			if !reflect.DeepEqual(fake.tagged, tc.expectedTagged) {
+				t.Errorf("tagged stages: got %v, expected %v", fake.tagged, tc.expectedTagged)
+			}
+		})
+	}
+}
+
+func TestIntegrationDeconfigureRestApi_ownership(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:           []string{"prod", "dev"},
+		configuredStages: []string{"prod", "dev"},
+		owners:           map[string]string{"prod": "other-integration", "dev": "this-integration"},
+		updates:          make(map[string][]string),
+		throttled:        map[string]bool{"a1b2c3d4e5": true},
+	}
+	in := testIntegration(t, fake)
+	in.id = "this-integration"
+
+	states := []StageState{
+		{restApiId: "a1b2c3d4e5", stageName: "dev", loggingLevel: "ERROR"},
+		{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "ERROR"},
+	}
+	result := in.deconfigureRestApi("a1b2c3d4e5", states)
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	var outcomes []string
+	for _, o := range result.stageOutcomes {
+		outcomes = append(outcomes, o.stageName+" "+o.outcome)
+	}
+	if expected := []string{"dev restored", "prod skipped"}; !reflect.DeepEqual(outcomes, expected) {
+		t.Errorf("outcomes: got %v, expected %v", outcomes, expected)
+	}
+	if expected := []string{"DELETE dev"}; !reflect.DeepEqual(fake.tagged, expected) {
+		t.Errorf("tagged stages: got %v, expected %v", fake.tagged, expected)
+	}
+}
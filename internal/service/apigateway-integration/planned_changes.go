@@ -0,0 +1,191 @@
+package apigatewayintegration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+)
+
+const (
+	plannedChangeActionConfigure = "configure"
+	plannedChangeActionRestore   = "restore"
+	plannedChangeActionSkip      = "skip"
+)
+
+// integrationConfigKeys are the arguments deciding which stages get which
+// configuration.
+var integrationConfigKeys = []string{"rest_api_ids", "api", "selector", "access_log_format", "access_log_destination_arn", "force_method_logging", "restore_policy", "data_trace", "data_trace_tags", "sensitive_tags", "stage_include", "stage_exclude", "rest_api_stage"}
+
+// plannedChange is a stage update the next apply makes. newStage is set for
+// stages created since the last apply.
+type plannedChange struct {
+	restApiId       string
+	stageName       string
+	action          string
+	newStage        bool
+	patchOperations []*apigateway.PatchOperation
+}
+
+// planRestApi returns the stage updates configureRestApi, or
+// deconfigureRestApi when configured is false, would make to the REST API.
+func (in *integration) planRestApi(restApiId string, states []StageState, configured bool) restApiResult {
+	result := restApiResult{restApiId: restApiId, states: states}
+	clients, err := in.targetClients(in.targetOf(restApiId))
+	if err != nil {
+		return result.failed("", err)
+	}
+	stages, err := in.findStages(clients, restApiId)
+	if tfresource.NotFound(err) {
+		return result
+	}
+	if err != nil {
+		return result.failed("", fmt.Errorf("reading stages: %w", err))
+	}
+	restApiTags, err := in.restApiTags(clients, restApiId)
+	if err != nil {
+		return result.failed("", fmt.Errorf("reading tags: %w", err))
+	}
+
+	for _, stage := range stages {
+		stageName := aws.StringValue(stage.StageName)
+		if !configured || !in.filter.match(restApiId, stageName) {
+			state := findStageState(states, restApiId, stageName)
+			if state == nil && !configured {
+				// deconfigureRestApi rebuilds snapshots lost with the
+				// Terraform state from the snapshot store.
+				if state, err = in.storedSnapshot(clients, restApiId, stage); err != nil {
+					return result.failed(stageName, err)
+				}
+			}
+			if state == nil {
+				continue
+			}
+			// Stages changed by someone else keep their settings.
+			if len(in.restoreConflict(clients, restApiId, stage, state, stageTags(restApiTags, stage))) > 0 {
+				result.plannedChanges = append(result.plannedChanges, plannedChange{
+					restApiId: restApiId,
+					stageName: stageName,
+					action:    plannedChangeActionSkip,
+				})
+				continue
+			}
+			result.plannedChanges = append(result.plannedChanges, plannedChange{
+				restApiId:       restApiId,
+				stageName:       stageName,
+				action:          plannedChangeActionRestore,
+				patchOperations: restoreStagePatchOperations(stage, state),
+			})
+			continue
+		}
+
+		// A stage owned by another integration, or data tracing on a
+		// sensitive stage, fails the plan before any stage is updated.
+		if err := in.checkStageOwner(stage); err != nil {
+			return result.failed(stageName, err)
+		}
+		tags := stageTags(restApiTags, stage)
+		config := in.stageConfiguration(clients, restApiId, stageName, tags)
+		if err := in.dataTrace.check(config, tags); err != nil {
+			return result.failed(stageName, err)
+		}
+		if len(stageDrift(stage, config)) == 0 {
+			continue
+		}
+		result.plannedChanges = append(result.plannedChanges, plannedChange{
+			restApiId:       restApiId,
+			stageName:       stageName,
+			action:          plannedChangeActionConfigure,
+			newStage:        findStageState(states, restApiId, stageName) == nil,
+			patchOperations: configureStagePatchOperations(stage, config),
+		})
+	}
+
+	return result
+}
+
+// resourceApiGatewayIntegrationPlannedChangesDiff sets planned_changes to the
+// stage updates of the apply, so the plan shows them per REST API and stage.
+func resourceApiGatewayIntegrationPlannedChangesDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	for _, key := range integrationConfigKeys {
+		if !d.NewValueKnown(key) {
+			if err := d.SetNewComputed("log_group_names"); err != nil {
+				return err
+			}
+			return d.SetNewComputed("planned_changes")
+		}
+	}
+
+	in, err := newIntegration(d, meta)
+	if err != nil {
+		return err
+	}
+
+	os, ns := restApiIdsChange(d)
+	restApiIds := append(sortedRestApiIds(os.Difference(ns)), sortedRestApiIds(ns)...)
+	allStates := expandStageStates(d.Get("rest_api_states").([]interface{}))
+	results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+		return in.planRestApi(restApiId, restApiStageStates(allStates, restApiId), ns.Contains(restApiId))
+	})
+
+	var errs *multierror.Error
+	changes, newStageChanges := []plannedChange{}, []plannedChange{}
+	for _, result := range results {
+		if result.err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", result.diagnostic("planning").Summary, result.err))
+			continue
+		}
+		for _, change := range result.plannedChanges {
+			if change.newStage {
+				newStageChanges = append(newStageChanges, change)
+			} else {
+				changes = append(changes, change)
+			}
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	// Stages created since the last apply are onboarded by any apply, but
+	// only make a plan of their own with enforce_new_stages.
+	if d.Id() == "" || d.Get("enforce_new_stages").(bool) || len(changes) > 0 ||
+		d.HasChanges(append(integrationConfigKeys, "selected_rest_api_ids")...) {
+		changes = append(changes, newStageChanges...)
+	}
+
+	// Configured and restored stages change the log groups to forward.
+	if len(changes) > 0 {
+		if err := d.SetNewComputed("log_group_names"); err != nil {
+			return err
+		}
+	}
+
+	return d.SetNew("planned_changes", flattenPlannedChanges(changes))
+}
+
+func flattenPlannedChanges(changes []plannedChange) []interface{} {
+	tfList := []interface{}{}
+	for _, change := range changes {
+		patchOperations := []interface{}{}
+		for _, op := range change.patchOperations {
+			patchOperation := fmt.Sprintf("%s %s", aws.StringValue(op.Op), aws.StringValue(op.Path))
+			if op.Value != nil {
+				patchOperation = fmt.Sprintf("%s %s", patchOperation, aws.StringValue(op.Value))
+			}
+			patchOperations = append(patchOperations, patchOperation)
+		}
+
+		tfList = append(tfList, map[string]interface{}{
+			"rest_api_id":      change.restApiId,
+			"stage":            change.stageName,
+			"action":           change.action,
+			"patch_operations": patchOperations,
+		})
+	}
+	return tfList
+}
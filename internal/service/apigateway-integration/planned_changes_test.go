@@ -0,0 +1,98 @@
+package apigatewayintegration
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntegrationPlanRestApi(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:           []string{"prod", "beta", "dev"},
+		configuredStages: []string{"dev"},
+		updates:          make(map[string][]string),
+		throttled:        make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+	in.filter = &stageFilter{exclude: []string{"dev"}}
+
+	states := []StageState{
+		{restApiId: "a1b2c3d4e5", stageName: "dev", loggingLevel: "ERROR"},
+	}
+	result := in.planRestApi("a1b2c3d4e5", states, true)
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	var actions []string
+	for _, change := range result.plannedChanges {
+		actions = append(actions, change.stageName+" "+change.action)
+	}
+	if expected := []string{"beta configure", "dev restore", "prod configure"}; !reflect.DeepEqual(actions, expected) {
+		t.Errorf("planned changes: got %v, expected %v", actions, expected)
+	}
+
+	tfList := flattenPlannedChanges(result.plannedChanges)
+	patchOperations := tfList[1].(map[string]interface{})["patch_operations"].([]interface{})
+	expected := []interface{}{
+		"replace /*/*/logging/loglevel ERROR",
+		"replace /*/*/logging/dataTrace false",
+		"replace /*/*/metrics/enabled false",
+		"remove /accessLogSettings",
+	}
+	if !reflect.DeepEqual(patchOperations, expected) {
+		t.Errorf("restore patch operations: got %v, expected %v", patchOperations, expected)
+	}
+
+	if updates := fake.updates["a1b2c3d4e5"]; len(updates) != 0 {
+		t.Errorf("expected no stage updates while planning, got %v", updates)
+	}
+}
+
+func TestIntegrationPlanRestApi_removed(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:           []string{"prod", "beta"},
+		configuredStages: []string{"prod"},
+		updates:          make(map[string][]string),
+		throttled:        make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+
+	states := []StageState{
+		{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "OFF", wildcardAbsent: true},
+	}
+	result := in.planRestApi("a1b2c3d4e5", states, false)
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	if len(result.plannedChanges) != 1 || result.plannedChanges[0].stageName != "prod" || result.plannedChanges[0].action != plannedChangeActionRestore {
+		t.Fatalf("expected a single restore of stage prod, got %v", result.plannedChanges)
+	}
+}
+
+func TestIntegrationPlanRestApi_newStages(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:    []string{"prod", "beta"},
+		updates:   make(map[string][]string),
+		throttled: make(map[string]bool),
+	}
+	in := testIntegration(t, fake)
+
+	// prod was configured with a different access log format, beta was
+	// created since.
+	states := []StageState{
+		{restApiId: "a1b2c3d4e5", stageName: "prod", loggingLevel: "OFF", wildcardAbsent: true},
+	}
+	result := in.planRestApi("a1b2c3d4e5", states, true)
+	if result.err != nil {
+		t.Fatalf("unexpected error: %s", result.err)
+	}
+
+	newStages := make(map[string]bool)
+	for _, change := range result.plannedChanges {
+		newStages[change.stageName] = change.newStage
+	}
+	if expected := map[string]bool{"beta": true, "prod": false}; !reflect.DeepEqual(newStages, expected) {
+		t.Errorf("new stages: got %v, expected %v", newStages, expected)
+	}
+}
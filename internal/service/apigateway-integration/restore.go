@@ -0,0 +1,141 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+)
+
+const (
+	restorePolicySafe  = "safe"
+	restorePolicyForce = "force"
+)
+
+func restorePolicy_Values() []string {
+	return []string{restorePolicySafe, restorePolicyForce}
+}
+
+const (
+	stageOutcomeRestored = "restored"
+	stageOutcomeSkipped  = "skipped"
+	stageOutcomeMissing  = "missing"
+)
+
+// stageOutcome is what restoring the snapshot of a stage did. detail says why
+// a stage was skipped.
+type stageOutcome struct {
+	stageName string
+	outcome   string
+	detail    string
+}
+
+// restoreConflict returns the settings of the stage changed by someone else
+// since the integration configured it. The stage is compared with the
+// configuration last applied to it, or the current one for snapshots not
+// recording it, so with restore_policy = "force", a stage still configured by
+// the integration or one still matching its snapshot, as when configuring it
+// failed, there is no conflict.
+func (in *integration) restoreConflict(clients *targetConns, restApiId string, stage *apigateway.Stage, state *StageState, tags map[string]*string) []string {
+	if in.restorePolicy == restorePolicyForce || snapshotMatches(stage, state) {
+		return nil
+	}
+	if state.applied != nil {
+		return stageDrift(stage, *state.applied)
+	}
+	return stageDrift(stage, in.stageConfiguration(clients, restApiId, aws.StringValue(stage.StageName), tags))
+}
+
+// snapshotMatches returns whether the logging settings of the stage are still
+// those of its snapshot.
+func snapshotMatches(stage *apigateway.Stage, state *StageState) bool {
+	current := extractStageState(state.restApiId, stage)
+	return current.wildcardAbsent == state.wildcardAbsent &&
+		current.loggingLevel == state.loggingLevel &&
+		current.dataTraceEnabled == state.dataTraceEnabled &&
+		current.accessLogsFormat == state.accessLogsFormat &&
+		current.accessLogsDestinationArn == state.accessLogsDestinationArn
+}
+
+// restoreStage writes the snapshot back to the stage, releases it and drops
+// the snapshot, also from the snapshot store. A stage changed by someone else
+// since the integration configured it keeps its settings, its claim and its
+// snapshot, so a later run with restore_policy = "force" can still restore
+// it. A stage taken over by another integration is left to it, along with
+// the stored snapshot.
+func (in *integration) restoreStage(clients *targetConns, result *restApiResult, stage *apigateway.Stage, state *StageState, tags map[string]*string) error {
+	stageName := aws.StringValue(stage.StageName)
+	if in.ownedByOther(stage) && !in.takeover {
+		result.stageOutcomes = append(result.stageOutcomes, stageOutcome{
+			stageName: stageName,
+			outcome:   stageOutcomeSkipped,
+			detail:    fmt.Sprintf("owned by noname_api_gateway_integration (%s)", stageOwner(stage)),
+		})
+		result.states = removeStageState(result.states, result.restApiId, stageName)
+		return nil
+	}
+
+	if conflict := in.restoreConflict(clients, result.restApiId, stage, state, tags); len(conflict) > 0 {
+		result.stageOutcomes = append(result.stageOutcomes, stageOutcome{
+			stageName: stageName,
+			outcome:   stageOutcomeSkipped,
+			detail:    fmt.Sprintf("changed since the integration configured it (%s)", strings.Join(conflict, ", ")),
+		})
+		return nil
+	}
+
+	if err := in.updateStage(clients, result.restApiId, stageName, restoreStagePatchOperations(stage, state)); err != nil {
+		return err
+	}
+	if err := in.releaseStage(clients, result.restApiId, stage); err != nil {
+		return err
+	}
+	if err := in.removeSnapshot(clients, result.restApiId, stageName); err != nil {
+		return err
+	}
+	result.stageOutcomes = append(result.stageOutcomes, stageOutcome{stageName: stageName, outcome: stageOutcomeRestored})
+	result.states = removeStageState(result.states, result.restApiId, stageName)
+	return nil
+}
+
+// skipped returns whether restoring the stage was skipped because of a
+// conflict, its snapshot kept.
+func (r restApiResult) skipped(stageName string) bool {
+	for _, o := range r.stageOutcomes {
+		if o.stageName == stageName && o.outcome == stageOutcomeSkipped {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreDiagnostic reports the outcome of every stage restored, skipped, or
+// deleted since the REST API was configured. It returns false when there is
+// nothing to report.
+func (r restApiResult) restoreDiagnostic() (diag.Diagnostic, bool) {
+	if len(r.stageOutcomes) == 0 {
+		return diag.Diagnostic{}, false
+	}
+
+	var lines []string
+	skipped := false
+	for _, o := range r.stageOutcomes {
+		line := fmt.Sprintf("%s: %s", o.stageName, o.outcome)
+		if o.detail != "" {
+			line = fmt.Sprintf("%s, %s", line, o.detail)
+		}
+		lines = append(lines, line)
+		skipped = skipped || o.outcome == stageOutcomeSkipped
+	}
+	if skipped {
+		lines = append(lines, fmt.Sprintf("Skipped stages keep their current settings and their snapshots, set restore_policy to %q to restore them anyway.", restorePolicyForce))
+	}
+
+	return diag.Diagnostic{
+		Severity: diag.Warning,
+		Summary:  fmt.Sprintf("restored API Gateway REST API (%s) stages", r.restApiId),
+		Detail:   strings.Join(lines, "\n"),
+	}, true
+}
@@ -0,0 +1,212 @@
+package apigatewayintegration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+)
+
+const integrationResourceType = "noname_api_gateway_integration"
+
+// RestoreCommandInput are the arguments of the restore subcommand of the
+// provider binary. The snapshots are read from a Terraform state file, from
+// a snapshot store given like in an import ID (<type>[:<location>]), or from
+// both.
+type RestoreCommandInput struct {
+	RestApiIds    []string
+	StateFile     string
+	SnapshotStore string
+	Parallelism   int
+	DryRun        bool
+}
+
+// RestoreCommand restores the snapshots of the stages of the REST APIs, as
+// destroying the integration does, and writes the outcome of every stage to
+// w. The integration configuration is not known here, so stages are restored
+// whatever changed them since, and whichever integration owns them. With
+// DryRun the patch operations are written instead.
+func RestoreCommand(client *conns.AWSClient, input RestoreCommandInput, w io.Writer) error {
+	if len(input.RestApiIds) == 0 {
+		return errors.New("no REST API IDs given")
+	}
+	if input.StateFile == "" && input.SnapshotStore == "" {
+		return errors.New("either a Terraform state file or a snapshot store is required")
+	}
+
+	in, err := newClientIntegration(client)
+	if err != nil {
+		return err
+	}
+	in.parallelism = input.Parallelism
+	in.restorePolicy = restorePolicyForce
+	in.takeover = true
+
+	if input.SnapshotStore != "" {
+		tfMap, err := importSnapshotStore(input.SnapshotStore)
+		if err != nil {
+			return err
+		}
+		if in.snapshots, err = expandSnapshotStore([]interface{}{tfMap}); err != nil {
+			return err
+		}
+	}
+
+	allStates := []StageState{}
+	if input.StateFile != "" {
+		if allStates, err = readStateFileStageStates(input.StateFile); err != nil {
+			return err
+		}
+		in.addStageStateTargets(allStates)
+	}
+
+	restApiIds := uniqueSortedStrings(input.RestApiIds)
+	if input.DryRun {
+		results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+			return in.planRestApi(restApiId, restApiStageStates(allStates, restApiId), false)
+		})
+		return writeRestorePlan(w, results)
+	}
+
+	results := in.forEachRestApi(restApiIds, func(restApiId string) restApiResult {
+		return in.deconfigureRestApi(restApiId, restApiStageStates(allStates, restApiId))
+	})
+	var errs *multierror.Error
+	for _, result := range results {
+		if d, ok := result.restoreDiagnostic(); ok {
+			fmt.Fprintf(w, "%s\n%s\n", d.Summary, d.Detail)
+		} else if result.err == nil {
+			fmt.Fprintf(w, "API Gateway REST API (%s) has no snapshots to restore\n", result.restApiId)
+		}
+		if result.err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", result.diagnostic("restoring").Summary, result.err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func writeRestorePlan(w io.Writer, results []restApiResult) error {
+	var errs *multierror.Error
+	for _, result := range results {
+		if result.err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", result.diagnostic("planning").Summary, result.err))
+			continue
+		}
+		if len(result.plannedChanges) == 0 {
+			fmt.Fprintf(w, "API Gateway REST API (%s) has no snapshots to restore\n", result.restApiId)
+			continue
+		}
+
+		for _, tfMapRaw := range flattenPlannedChanges(result.plannedChanges) {
+			tfMap := tfMapRaw.(map[string]interface{})
+			fmt.Fprintf(w, "API Gateway REST API (%s) stage (%s): %s\n", tfMap["rest_api_id"], tfMap["stage"], tfMap["action"])
+			for _, op := range tfMap["patch_operations"].([]interface{}) {
+				fmt.Fprintf(w, "  %s\n", op)
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// stateFileStageState is a snapshot as written to a Terraform state file.
+type stateFileStageState struct {
+	RestApiId                string `json:"rest_api_id"`
+	Stage                    string `json:"stage"`
+	LoggingLevel             string `json:"logging_level"`
+	DataTraceEnabled         bool   `json:"data_trace_enabled"`
+	MetricsEnabled           bool   `json:"metrics_enabled"`
+	AccessLogsFormat         string `json:"access_log_format"`
+	AccessLogsDestinationArn string `json:"access_log_destination_arn"`
+	Region                   string `json:"region"`
+	AccountId                string `json:"account_id"`
+	AssumeRoleArn            string `json:"assume_role_arn"`
+	AssumeRoleExternalId     string `json:"assume_role_external_id"`
+	AssumeRoleSessionName    string `json:"assume_role_session_name"`
+	WildcardAbsent           bool   `json:"wildcard_absent"`
+	MetricsUnknown           bool   `json:"metrics_unknown"`
+	MethodSettings           []struct {
+		MethodPath       string `json:"method_path"`
+		LoggingLevel     string `json:"logging_level"`
+		DataTraceEnabled bool   `json:"data_trace_enabled"`
+		MetricsEnabled   bool   `json:"metrics_enabled"`
+	} `json:"method_settings"`
+}
+
+// readStateFileStageStates returns the snapshots of all integrations of a
+// Terraform state file, such as a backup taken before the state was lost.
+func readStateFileStageStates(path string) ([]StageState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading Terraform state file (%s): %w", path, err)
+	}
+
+	var state struct {
+		Resources []struct {
+			Type      string `json:"type"`
+			Instances []struct {
+				Attributes struct {
+					RestApiStates []stateFileStageState `json:"rest_api_states"`
+				} `json:"attributes"`
+			} `json:"instances"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decoding Terraform state file (%s): %w", path, err)
+	}
+
+	states := []StageState{}
+	for _, resource := range state.Resources {
+		if resource.Type != integrationResourceType {
+			continue
+		}
+		for _, instance := range resource.Instances {
+			for _, v := range instance.Attributes.RestApiStates {
+				stageState := StageState{
+					restApiId:                v.RestApiId,
+					stageName:                v.Stage,
+					loggingLevel:             v.LoggingLevel,
+					dataTraceEnabled:         v.DataTraceEnabled,
+					metricsEnabled:           v.MetricsEnabled,
+					accessLogsFormat:         v.AccessLogsFormat,
+					accessLogsDestinationArn: v.AccessLogsDestinationArn,
+					region:                   v.Region,
+					accountId:                v.AccountId,
+					assumeRoleArn:            v.AssumeRoleArn,
+					assumeRoleExternalId:     v.AssumeRoleExternalId,
+					assumeRoleSessionName:    v.AssumeRoleSessionName,
+					wildcardAbsent:           v.WildcardAbsent,
+					metricsUnknown:           v.MetricsUnknown,
+					methodSettings:           []methodSettingsState{},
+				}
+				for _, settings := range v.MethodSettings {
+					stageState.methodSettings = append(stageState.methodSettings, methodSettingsState{
+						methodPath:       settings.MethodPath,
+						loggingLevel:     settings.LoggingLevel,
+						dataTraceEnabled: settings.DataTraceEnabled,
+						metricsEnabled:   settings.MetricsEnabled,
+					})
+				}
+				states = append(states, stageState)
+			}
+		}
+	}
+	return states, nil
+}
+
+func uniqueSortedStrings(values []string) []string {
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
@@ -0,0 +1,123 @@
+package apigatewayintegration
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+)
+
+const testStateFile = `{
+  "version": 4,
+  "resources": [
+    {
+      "type": "noname_api_gateway_integration",
+      "instances": [
+        {
+          "attributes": {
+            "rest_api_states": [
+              {"rest_api_id": "a1b2c3d4e5", "stage": "prod", "logging_level": "ERROR", "data_trace_enabled": false, "metrics_enabled": false, "access_log_format": "", "access_log_destination_arn": "", "region": "", "wildcard_absent": false, "method_settings": []},
+              {"rest_api_id": "f6g7h8i9j0", "stage": "prod", "logging_level": "OFF", "wildcard_absent": true}
+            ]
+          }
+        }
+      ]
+    },
+    {
+      "type": "aws_api_gateway_stage",
+      "instances": [{"attributes": {"rest_api_id": "a1b2c3d4e5", "stage_name": "prod"}}]
+    }
+  ]
+}`
+
+func TestReadStateFileStageStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+	if err := os.WriteFile(path, []byte(testStateFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := readStateFileStageStates(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var identifiers []string
+	for _, state := range states {
+		identifiers = append(identifiers, state.restApiId+"-"+state.stageName)
+	}
+	if expected := "a1b2c3d4e5-prod f6g7h8i9j0-prod"; strings.Join(identifiers, " ") != expected {
+		t.Errorf("snapshots: got %v, expected %s", identifiers, expected)
+	}
+	if !states[1].wildcardAbsent || states[1].loggingLevel != loggingLevelOff {
+		t.Errorf("unexpected snapshot: %v", states[1])
+	}
+}
+
+func TestRestoreCommand_dryRun(t *testing.T) {
+	fake := &fakeAPIGateway{
+		stages:    []string{"prod", "dev"},
+		updates:   make(map[string][]string),
+		throttled: make(map[string]bool),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/restapis/") {
+			fake.ServeHTTP(w, r)
+			return
+		}
+		fakeSTS(w, r)
+	}))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	client := &conns.AWSClient{
+		APIGatewayConn: apigateway.New(sess),
+		STSConn:        sts.New(sess),
+		Session:        sess,
+		Region:         "us-east-1", //lintignore:AWSAT003
+		Partition:      "aws",
+	}
+
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+	if err := os.WriteFile(path, []byte(testStateFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err := RestoreCommand(client, RestoreCommandInput{
+		RestApiIds: []string{"a1b2c3d4e5", "k1l2m3n4o5"},
+		StateFile:  path,
+		DryRun:     true,
+	}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := `API Gateway REST API (a1b2c3d4e5) stage (prod): restore
+  replace /*/*/logging/loglevel ERROR
+  replace /*/*/logging/dataTrace false
+  replace /*/*/metrics/enabled false
+  remove /accessLogSettings
+API Gateway REST API (k1l2m3n4o5) has no snapshots to restore
+`
+	if out.String() != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+	if updates := fake.updates["a1b2c3d4e5"]; len(updates) != 0 {
+		t.Errorf("expected no stage updates on a dry run, got %v", updates)
+	}
+}
@@ -0,0 +1,424 @@
+package apigatewayintegration
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+)
+
+const (
+	snapshotStoreSSM      = "ssm"
+	snapshotStoreStageTag = "stage_tag"
+	snapshotStoreFile     = "file"
+)
+
+func snapshotStore_Values() []string {
+	return []string{snapshotStoreSSM, snapshotStoreStageTag, snapshotStoreFile}
+}
+
+const (
+	defaultSnapshotStoreSSMPrefix = "/noname/api-gateway-integration"
+	snapshotTagKeyPrefix          = "noname:snapshot:"
+	snapshotTagValueMaxLength     = 256
+	// stageTagsMaxCount is the number of tags a stage can have, shared by
+	// the snapshot and the own tags of the stage.
+	stageTagsMaxCount = 50
+)
+
+// snapshotStore keeps the snapshots of the stages outside of the Terraform
+// state, so they survive a lost state and can be restored after an import.
+// get returns nil when no snapshot of the stage is stored.
+type snapshotStore interface {
+	put(clients *targetConns, state StageState) error
+	get(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error)
+	remove(clients *targetConns, restApiId string, stageName string) error
+}
+
+// storedStageState is the compact JSON encoding of a snapshot. The REST API,
+// stage and target are implied by where the snapshot is stored.
+type storedStageState struct {
+	LoggingLevel             string                      `json:"l,omitempty"`
+	DataTraceEnabled         bool                        `json:"d,omitempty"`
+	MetricsEnabled           bool                        `json:"m,omitempty"`
+	AccessLogsFormat         string                      `json:"f,omitempty"`
+	AccessLogsDestinationArn string                      `json:"a,omitempty"`
+	WildcardAbsent           bool                        `json:"w,omitempty"`
+	MetricsUnknown           bool                        `json:"u,omitempty"`
+	MethodSettings           []storedMethodSettingsState `json:"o,omitempty"`
+}
+
+type storedMethodSettingsState struct {
+	MethodPath       string `json:"p"`
+	LoggingLevel     string `json:"l,omitempty"`
+	DataTraceEnabled bool   `json:"d,omitempty"`
+	MetricsEnabled   bool   `json:"m,omitempty"`
+}
+
+func marshalStageState(state StageState) ([]byte, error) {
+	stored := storedStageState{
+		LoggingLevel:             state.loggingLevel,
+		DataTraceEnabled:         state.dataTraceEnabled,
+		MetricsEnabled:           state.metricsEnabled,
+		AccessLogsFormat:         state.accessLogsFormat,
+		AccessLogsDestinationArn: state.accessLogsDestinationArn,
+		WildcardAbsent:           state.wildcardAbsent,
+		MetricsUnknown:           state.metricsUnknown,
+	}
+	for _, settings := range state.methodSettings {
+		stored.MethodSettings = append(stored.MethodSettings, storedMethodSettingsState{
+			MethodPath:       settings.methodPath,
+			LoggingLevel:     settings.loggingLevel,
+			DataTraceEnabled: settings.dataTraceEnabled,
+			MetricsEnabled:   settings.metricsEnabled,
+		})
+	}
+	return json.Marshal(stored)
+}
+
+func unmarshalStageState(clients *targetConns, restApiId string, stageName string, data []byte) (*StageState, error) {
+	var stored storedStageState
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("decoding snapshot of API Gateway REST API (%s) stage (%s): %w", restApiId, stageName, err)
+	}
+
+	state := &StageState{
+		restApiId:                restApiId,
+		stageName:                stageName,
+		loggingLevel:             stored.LoggingLevel,
+		dataTraceEnabled:         stored.DataTraceEnabled,
+		metricsEnabled:           stored.MetricsEnabled,
+		accessLogsFormat:         stored.AccessLogsFormat,
+		accessLogsDestinationArn: stored.AccessLogsDestinationArn,
+		wildcardAbsent:           stored.WildcardAbsent,
+		metricsUnknown:           stored.MetricsUnknown,
+		methodSettings:           []methodSettingsState{},
+	}
+	for _, settings := range stored.MethodSettings {
+		state.methodSettings = append(state.methodSettings, methodSettingsState{
+			methodPath:       settings.MethodPath,
+			loggingLevel:     settings.LoggingLevel,
+			dataTraceEnabled: settings.DataTraceEnabled,
+			metricsEnabled:   settings.MetricsEnabled,
+		})
+	}
+	clients.setTarget(state)
+	return state, nil
+}
+
+// expandSnapshotStore returns the store of the snapshot_store block, or nil
+// when snapshots are only kept in the Terraform state.
+func expandSnapshotStore(tfList []interface{}) (snapshotStore, error) {
+	if len(tfList) == 0 || tfList[0] == nil {
+		return nil, nil
+	}
+
+	tfMap := tfList[0].(map[string]interface{})
+	switch storeType := tfMap["type"].(string); storeType {
+	case snapshotStoreSSM:
+		prefix := strings.TrimSuffix(tfMap["ssm_prefix"].(string), "/")
+		if prefix == "" {
+			prefix = defaultSnapshotStoreSSMPrefix
+		}
+		return ssmSnapshotStore{prefix: prefix}, nil
+	case snapshotStoreStageTag:
+		return stageTagSnapshotStore{}, nil
+	case snapshotStoreFile:
+		directory := tfMap["directory"].(string)
+		if directory == "" {
+			return nil, fmt.Errorf("snapshot_store directory is required with type %q", snapshotStoreFile)
+		}
+		return fileSnapshotStore{directory: directory}, nil
+	default:
+		return nil, fmt.Errorf("unsupported snapshot_store type (%s)", storeType)
+	}
+}
+
+// importSnapshotStore returns the snapshot_store block of the part of an
+// import ID like <type>[:<location>], the location being the SSM parameter
+// prefix or the directory of the files.
+func importSnapshotStore(v string) (map[string]interface{}, error) {
+	parts := strings.SplitN(v, ":", 2)
+	tfMap := map[string]interface{}{
+		"type":       parts[0],
+		"ssm_prefix": defaultSnapshotStoreSSMPrefix,
+		"directory":  "",
+	}
+
+	switch parts[0] {
+	case snapshotStoreSSM:
+		if len(parts) == 2 && parts[1] != "" {
+			tfMap["ssm_prefix"] = parts[1]
+		}
+	case snapshotStoreStageTag:
+	case snapshotStoreFile:
+		if len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("unexpected format of snapshot store (%s), expected %s:<directory>", v, snapshotStoreFile)
+		}
+		tfMap["directory"] = parts[1]
+	default:
+		return nil, fmt.Errorf("unexpected snapshot store type (%s), expected one of %s", parts[0], strings.Join(snapshotStore_Values(), ", "))
+	}
+	return tfMap, nil
+}
+
+// ssmSnapshotStore keeps every snapshot in an SSM parameter named
+// <prefix>/<rest_api_id>/<stage> in the account and region of the REST API.
+type ssmSnapshotStore struct {
+	prefix string
+}
+
+func (s ssmSnapshotStore) name(restApiId string, stageName string) string {
+	return fmt.Sprintf("%s/%s/%s", s.prefix, restApiId, stageName)
+}
+
+func (s ssmSnapshotStore) put(clients *targetConns, state StageState) error {
+	data, err := marshalStageState(state)
+	if err != nil {
+		return err
+	}
+
+	name := s.name(state.restApiId, state.stageName)
+	_, err = clients.ssmConn.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Overwrite: aws.Bool(true),
+		Tier:      aws.String(ssm.ParameterTierIntelligentTiering),
+		Type:      aws.String(ssm.ParameterTypeString),
+		Value:     aws.String(string(data)),
+	})
+	if err != nil {
+		return fmt.Errorf("putting SSM Parameter (%s): %w", name, err)
+	}
+	return nil
+}
+
+func (s ssmSnapshotStore) get(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error) {
+	stageName := aws.StringValue(stage.StageName)
+	name := s.name(restApiId, stageName)
+	output, err := clients.ssmConn.GetParameter(&ssm.GetParameterInput{
+		Name: aws.String(name),
+	})
+	if tfawserr.ErrCodeEquals(err, ssm.ErrCodeParameterNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading SSM Parameter (%s): %w", name, err)
+	}
+	if output == nil || output.Parameter == nil {
+		return nil, nil
+	}
+
+	return unmarshalStageState(clients, restApiId, stageName, []byte(aws.StringValue(output.Parameter.Value)))
+}
+
+func (s ssmSnapshotStore) remove(clients *targetConns, restApiId string, stageName string) error {
+	name := s.name(restApiId, stageName)
+	_, err := clients.ssmConn.DeleteParameter(&ssm.DeleteParameterInput{
+		Name: aws.String(name),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, ssm.ErrCodeParameterNotFound) {
+		return fmt.Errorf("deleting SSM Parameter (%s): %w", name, err)
+	}
+	return nil
+}
+
+// stageTagSnapshotStore keeps every snapshot in tags of its stage, so it
+// moves with the stage. The base64 encoded JSON is split over as many
+// noname:snapshot:<n> tags as the tag value length requires.
+type stageTagSnapshotStore struct{}
+
+func stageArn(clients *targetConns, restApiId string, stageName string) string {
+	return arn.ARN{
+		Partition: clients.partition,
+		Service:   apigateway.ServiceName,
+		Region:    clients.region,
+		Resource:  fmt.Sprintf("/restapis/%s/stages/%s", restApiId, stageName),
+	}.String()
+}
+
+// snapshotTags returns the tags holding the snapshot.
+func snapshotTags(state StageState) (map[string]string, error) {
+	data, err := marshalStageState(state)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	tags := make(map[string]string)
+	for i := 0; len(encoded) > 0; i++ {
+		n := len(encoded)
+		if n > snapshotTagValueMaxLength {
+			n = snapshotTagValueMaxLength
+		}
+		tags[snapshotTagKeyPrefix+strconv.Itoa(i)] = encoded[:n]
+		encoded = encoded[n:]
+	}
+	return tags, nil
+}
+
+// snapshotFromTags returns the snapshot held by the tags of a stage, or nil
+// when it has none.
+func snapshotFromTags(clients *targetConns, restApiId string, stageName string, tags map[string]*string) (*StageState, error) {
+	var encoded strings.Builder
+	for i := 0; ; i++ {
+		v, ok := tags[snapshotTagKeyPrefix+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		encoded.WriteString(aws.StringValue(v))
+	}
+	if encoded.Len() == 0 {
+		return nil, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding snapshot of API Gateway REST API (%s) stage (%s): %w", restApiId, stageName, err)
+	}
+	return unmarshalStageState(clients, restApiId, stageName, data)
+}
+
+func (s stageTagSnapshotStore) put(clients *targetConns, state StageState) error {
+	tags, err := snapshotTags(state)
+	if err != nil {
+		return err
+	}
+
+	resourceArn := stageArn(clients, state.restApiId, state.stageName)
+	previous, err := s.snapshotTagKeys(clients, resourceArn)
+	if err != nil {
+		return err
+	}
+
+	if free := stageTagsMaxCount - previous.otherCount; len(tags) > free {
+		return fmt.Errorf("snapshot of API Gateway Stage (%s) needs %d tags, but the stage has only %d of its %d tags free; use the %q or %q snapshot store instead", resourceArn, len(tags), free, stageTagsMaxCount, snapshotStoreSSM, snapshotStoreFile)
+	}
+
+	// Drop the tags of a longer previous snapshot first.
+	if err := s.untag(clients, resourceArn, previous.keys); err != nil {
+		return err
+	}
+
+	_, err = clients.conn.TagResource(&apigateway.TagResourceInput{
+		ResourceArn: aws.String(resourceArn),
+		Tags:        aws.StringMap(tags),
+	})
+	if err != nil {
+		return fmt.Errorf("tagging API Gateway Stage (%s): %w", resourceArn, err)
+	}
+	return nil
+}
+
+func (s stageTagSnapshotStore) get(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error) {
+	return snapshotFromTags(clients, restApiId, aws.StringValue(stage.StageName), stage.Tags)
+}
+
+func (s stageTagSnapshotStore) remove(clients *targetConns, restApiId string, stageName string) error {
+	resourceArn := stageArn(clients, restApiId, stageName)
+	previous, err := s.snapshotTagKeys(clients, resourceArn)
+	if err != nil {
+		return err
+	}
+	return s.untag(clients, resourceArn, previous.keys)
+}
+
+// stageTagKeys are the keys of the snapshot tags of a stage, and the number
+// of its other tags.
+type stageTagKeys struct {
+	keys       []string
+	otherCount int
+}
+
+func (s stageTagSnapshotStore) snapshotTagKeys(clients *targetConns, resourceArn string) (stageTagKeys, error) {
+	var result stageTagKeys
+	output, err := clients.conn.GetTags(&apigateway.GetTagsInput{
+		ResourceArn: aws.String(resourceArn),
+	})
+	if tfawserr.ErrCodeEquals(err, apigateway.ErrCodeNotFoundException) {
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("reading API Gateway Stage (%s) tags: %w", resourceArn, err)
+	}
+
+	for k := range output.Tags {
+		if strings.HasPrefix(k, snapshotTagKeyPrefix) {
+			result.keys = append(result.keys, k)
+		} else {
+			result.otherCount++
+		}
+	}
+	return result, nil
+}
+
+func (s stageTagSnapshotStore) untag(clients *targetConns, resourceArn string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := clients.conn.UntagResource(&apigateway.UntagResourceInput{
+		ResourceArn: aws.String(resourceArn),
+		TagKeys:     aws.StringSlice(keys),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, apigateway.ErrCodeNotFoundException) {
+		return fmt.Errorf("untagging API Gateway Stage (%s): %w", resourceArn, err)
+	}
+	return nil
+}
+
+// fileSnapshotStore keeps every snapshot in a local file named
+// <directory>/<rest_api_id>/<stage>.json.
+type fileSnapshotStore struct {
+	directory string
+}
+
+func (s fileSnapshotStore) path(restApiId string, stageName string) string {
+	return filepath.Join(s.directory, restApiId, stageName+".json")
+}
+
+func (s fileSnapshotStore) put(clients *targetConns, state StageState) error {
+	data, err := marshalStageState(state)
+	if err != nil {
+		return err
+	}
+
+	path := s.path(state.restApiId, state.stageName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating snapshot directory (%s): %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("writing snapshot file (%s): %w", path, err)
+	}
+	return nil
+}
+
+func (s fileSnapshotStore) get(clients *targetConns, restApiId string, stage *apigateway.Stage) (*StageState, error) {
+	stageName := aws.StringValue(stage.StageName)
+	path := s.path(restApiId, stageName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot file (%s): %w", path, err)
+	}
+
+	return unmarshalStageState(clients, restApiId, stageName, data)
+}
+
+func (s fileSnapshotStore) remove(clients *targetConns, restApiId string, stageName string) error {
+	path := s.path(restApiId, stageName)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing snapshot file (%s): %w", path, err)
+	}
+	return nil
+}
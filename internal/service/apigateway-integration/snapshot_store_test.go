@@ -0,0 +1,228 @@
+package apigatewayintegration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+)
+
+func testStageState() StageState {
+	return StageState{
+		restApiId:                "a1b2c3d4e5",
+		stageName:                "prod",
+		loggingLevel:             "ERROR",
+		metricsEnabled:           true,
+		accessLogsFormat:         expandAccessLogFormat(accessLogFormatNonameJSONV1),
+		accessLogsDestinationArn: "arn:aws:logs:us-east-1:123456789012:log-group:access", //lintignore:AWSAT003,AWSAT005
+		region:                   "us-east-1",                                            //lintignore:AWSAT003
+		methodSettings: []methodSettingsState{
+			{methodPath: "pets/GET", loggingLevel: "OFF", metricsEnabled: true},
+		},
+	}
+}
+
+func TestSnapshotTags(t *testing.T) {
+	clients := &targetConns{region: "us-east-1"} //lintignore:AWSAT003
+	state := testStageState()
+
+	tags, err := snapshotTags(state)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tags) < 2 {
+		t.Errorf("expected the snapshot to be split over several tags, got %d", len(tags))
+	}
+	for k, v := range tags {
+		if len(v) > snapshotTagValueMaxLength {
+			t.Errorf("tag (%s) value is %d characters long", k, len(v))
+		}
+	}
+
+	actual, err := snapshotFromTags(clients, state.restApiId, state.stageName, aws.StringMap(tags))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(*actual, state) {
+		t.Errorf("got %v, expected %v", *actual, state)
+	}
+
+	if actual, err := snapshotFromTags(clients, state.restApiId, state.stageName, nil); err != nil || actual != nil {
+		t.Errorf("untagged stage: got %v, %v, expected no snapshot", actual, err)
+	}
+}
+
+func TestStageTagSnapshotStorePut(t *testing.T) {
+	large := testStageState()
+	for i := 0; i < 300; i++ {
+		large.methodSettings = append(large.methodSettings, methodSettingsState{
+			methodPath:     fmt.Sprintf("resource%d/GET", i),
+			loggingLevel:   "INFO",
+			metricsEnabled: true,
+		})
+	}
+
+	testCases := []struct {
+		name          string
+		state         StageState
+		expectedError bool
+	}{
+		{
+			name:  "snapshot",
+			state: testStageState(),
+		},
+		{
+			name:          "large snapshot",
+			state:         large,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// The stage has 40 tags of its own and an old snapshot.
+			tags := map[string]string{
+				IntegrationIdTagKey:        "integration",
+				snapshotTagKeyPrefix + "0": "old",
+			}
+			for i := 1; i < 40; i++ {
+				tags[fmt.Sprintf("tag%d", i)] = "value"
+			}
+			var calls []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, r.Method)
+				if r.Method == http.MethodGet {
+					json.NewEncoder(w).Encode(map[string]interface{}{"tags": tags})
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			sess := session.Must(session.NewSession(&aws.Config{
+				Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+				Endpoint:    aws.String(server.URL),
+				Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+				MaxRetries:  aws.Int(0),
+			}))
+			clients := &targetConns{
+				conn:      apigateway.New(sess),
+				partition: "aws",
+				region:    "us-east-1", //lintignore:AWSAT003
+			}
+
+			err := stageTagSnapshotStore{}.put(clients, tc.state)
+			if !tc.expectedError {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if expected := []string{http.MethodGet, http.MethodDelete, http.MethodPut}; !reflect.DeepEqual(calls, expected) {
+					t.Errorf("got calls %v, expected %v", calls, expected)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if !strings.Contains(err.Error(), `"ssm"`) || !strings.Contains(err.Error(), `"file"`) {
+				t.Errorf("expected the error to recommend the ssm and file stores, got: %s", err)
+			}
+			// The old snapshot is kept.
+			if expected := []string{http.MethodGet}; !reflect.DeepEqual(calls, expected) {
+				t.Errorf("got calls %v, expected %v", calls, expected)
+			}
+		})
+	}
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	clients := &targetConns{region: "us-east-1"} //lintignore:AWSAT003
+	store := fileSnapshotStore{directory: t.TempDir()}
+	state := testStageState()
+	stage := &apigateway.Stage{StageName: aws.String(state.stageName)}
+
+	if actual, err := store.get(clients, state.restApiId, stage); err != nil || actual != nil {
+		t.Fatalf("before put: got %v, %v, expected no snapshot", actual, err)
+	}
+
+	if err := store.put(clients, state); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	actual, err := store.get(clients, state.restApiId, stage)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(*actual, state) {
+		t.Errorf("got %v, expected %v", *actual, state)
+	}
+
+	if err := store.remove(clients, state.restApiId, state.stageName); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := store.remove(clients, state.restApiId, state.stageName); err != nil {
+		t.Errorf("removing a removed snapshot: %s", err)
+	}
+	if actual, err := store.get(clients, state.restApiId, stage); err != nil || actual != nil {
+		t.Errorf("after remove: got %v, %v, expected no snapshot", actual, err)
+	}
+}
+
+func TestImportSnapshotStore(t *testing.T) {
+	testCases := []struct {
+		value     string
+		expected  map[string]interface{}
+		expectErr bool
+	}{
+		{
+			value:    "ssm",
+			expected: map[string]interface{}{"type": "ssm", "ssm_prefix": defaultSnapshotStoreSSMPrefix, "directory": ""},
+		},
+		{
+			value:    "ssm:/noname/snapshots",
+			expected: map[string]interface{}{"type": "ssm", "ssm_prefix": "/noname/snapshots", "directory": ""},
+		},
+		{
+			value:    "stage_tag",
+			expected: map[string]interface{}{"type": "stage_tag", "ssm_prefix": defaultSnapshotStoreSSMPrefix, "directory": ""},
+		},
+		{
+			value:    "file:/var/lib/noname",
+			expected: map[string]interface{}{"type": "file", "ssm_prefix": defaultSnapshotStoreSSMPrefix, "directory": "/var/lib/noname"},
+		},
+		{
+			value:     "file",
+			expectErr: true,
+		},
+		{
+			value:     "s3:bucket",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			actual, err := importSnapshotStore(tc.value)
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("got %v, expected %v", actual, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package apigatewayintegration
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/flex"
+)
+
+// stageFilter selects the stages of the integrated REST APIs that get the
+// Noname logging configuration.
+type stageFilter struct {
+	include       []string
+	exclude       []string
+	restApiStages map[string][]string
+}
+
+func expandStageFilter(d resourceData) *stageFilter {
+	filter := &stageFilter{
+		restApiStages: make(map[string][]string),
+	}
+
+	if v, ok := d.GetOk("stage_include"); ok {
+		filter.include = aws.StringValueSlice(flex.ExpandStringSet(v.(*schema.Set)))
+	}
+
+	if v, ok := d.GetOk("stage_exclude"); ok {
+		filter.exclude = aws.StringValueSlice(flex.ExpandStringSet(v.(*schema.Set)))
+	}
+
+	if v, ok := d.GetOk("rest_api_stage"); ok {
+		for _, tfMapRaw := range v.(*schema.Set).List() {
+			tfMap := tfMapRaw.(map[string]interface{})
+			restApiId := tfMap["rest_api_id"].(string)
+			stages := aws.StringValueSlice(flex.ExpandStringSet(tfMap["stages"].(*schema.Set)))
+			filter.restApiStages[restApiId] = append(filter.restApiStages[restApiId], stages...)
+		}
+	}
+
+	return filter
+}
+
+// match reports whether the stage should be configured. An explicit stage
+// list for the REST API takes precedence over the include patterns, the
+// exclude patterns always apply.
+func (f *stageFilter) match(restApiId string, stageName string) bool {
+	if stages, ok := f.restApiStages[restApiId]; ok {
+		if !matchAnyStagePattern(stages, stageName) {
+			return false
+		}
+	} else if len(f.include) > 0 && !matchAnyStagePattern(f.include, stageName) {
+		return false
+	}
+
+	return !matchAnyStagePattern(f.exclude, stageName)
+}
+
+func matchAnyStagePattern(patterns []string, stageName string) bool {
+	for _, pattern := range patterns {
+		if matchStagePattern(pattern, stageName) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStagePattern matches a stage name against a glob pattern, or against a
+// regular expression when the pattern is wrapped in "/".
+func matchStagePattern(pattern string, stageName string) bool {
+	if expr, ok := stagePatternRegexp(pattern); ok {
+		re, err := regexp.Compile(expr)
+		return err == nil && re.MatchString(stageName)
+	}
+
+	matched, err := path.Match(pattern, stageName)
+	return err == nil && matched
+}
+
+func stagePatternRegexp(pattern string) (string, bool) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return pattern[1 : len(pattern)-1], true
+	}
+	return "", false
+}
+
+func validStagePattern(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+
+	if expr, ok := stagePatternRegexp(value); ok {
+		if _, err := regexp.Compile(expr); err != nil {
+			errors = append(errors, fmt.Errorf("%q contains an invalid regular expression %q: %w", k, value, err))
+		}
+		return
+	}
+
+	if _, err := path.Match(value, ""); err != nil {
+		errors = append(errors, fmt.Errorf("%q contains an invalid glob pattern %q: %w", k, value, err))
+	}
+
+	return
+}
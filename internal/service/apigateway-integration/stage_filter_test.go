@@ -0,0 +1,69 @@
+package apigatewayintegration
+
+import (
+	"testing"
+)
+
+func TestStageFilterMatch(t *testing.T) {
+	filter := &stageFilter{
+		include: []string{"prod*", "/^v[0-9]+$/"},
+		exclude: []string{"*-pci"},
+		restApiStages: map[string][]string{
+			"a1b2c3d4e5": {"dev-*"},
+		},
+	}
+
+	testCases := []struct {
+		restApiId string
+		stageName string
+		expected  bool
+	}{
+		{restApiId: "f6g7h8i9j0", stageName: "prod", expected: true},
+		{restApiId: "f6g7h8i9j0", stageName: "production", expected: true},
+		{restApiId: "f6g7h8i9j0", stageName: "v2", expected: true},
+		{restApiId: "f6g7h8i9j0", stageName: "v2-beta", expected: false},
+		{restApiId: "f6g7h8i9j0", stageName: "dev-alice", expected: false},
+		{restApiId: "f6g7h8i9j0", stageName: "prod-pci", expected: false},
+		{restApiId: "a1b2c3d4e5", stageName: "dev-alice", expected: true},
+		{restApiId: "a1b2c3d4e5", stageName: "prod", expected: false},
+		{restApiId: "a1b2c3d4e5", stageName: "dev-pci", expected: false},
+	}
+
+	for _, tc := range testCases {
+		if actual := filter.match(tc.restApiId, tc.stageName); actual != tc.expected {
+			t.Errorf("match(%q, %q) = %t, expected %t", tc.restApiId, tc.stageName, actual, tc.expected)
+		}
+	}
+}
+
+func TestStageFilterMatch_empty(t *testing.T) {
+	filter := &stageFilter{}
+
+	if !filter.match("a1b2c3d4e5", "prod") {
+		t.Error("expected empty filter to match every stage")
+	}
+}
+
+func TestValidStagePattern(t *testing.T) {
+	validPatterns := []string{
+		"prod",
+		"dev-*",
+		"v[0-9]",
+		"/^dev-.+$/",
+	}
+	for _, v := range validPatterns {
+		if _, errors := validStagePattern(v, "stage_include"); len(errors) != 0 {
+			t.Fatalf("%q should be a valid stage pattern: %q", v, errors)
+		}
+	}
+
+	invalidPatterns := []string{
+		"v[0-9",
+		"/^dev-(.+$/",
+	}
+	for _, v := range invalidPatterns {
+		if _, errors := validStagePattern(v, "stage_include"); len(errors) == 0 {
+			t.Fatalf("%q should be an invalid stage pattern", v)
+		}
+	}
+}
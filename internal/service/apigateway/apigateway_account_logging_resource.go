@@ -0,0 +1,358 @@
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+const (
+	accountLoggingDefaultRoleName = "noname-api-gateway-cloudwatch-logs"
+	accountLoggingPolicyARNSuffix = "iam::aws:policy/service-role/AmazonAPIGatewayPushToCloudWatchLogs"
+	accountLoggingPropagation     = 2 * time.Minute
+)
+
+const accountLoggingAssumeRolePolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "Service": "apigateway.amazonaws.com"
+      },
+      "Action": "sts:AssumeRole"
+    }
+  ]
+}`
+
+func ResourceApiGatewayAccountLogging() *schema.Resource {
+	return &schema.Resource{
+		Description: `Sets the IAM role API Gateway uses to write execution and access logs to CloudWatch Logs
+in the configured region. The role is created with the AWS managed AmazonAPIGatewayPushToCloudWatchLogs policy
+unless an existing role is given. The previous role of the account is restored on destroy.`,
+		Read:   resourceApiGatewayAccountLoggingRead,
+		Create: resourceApiGatewayAccountLoggingCreate,
+		Delete: resourceApiGatewayAccountLoggingDelete,
+		Update: resourceApiGatewayAccountLoggingUpdate,
+
+		CustomizeDiff: resourceApiGatewayAccountLoggingRoleDiff,
+
+		Schema: map[string]*schema.Schema{
+			"role_arn": {
+				Description:  `ARN of an existing IAM role to use. A role is created when not set.`,
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: verify.ValidARN,
+			},
+			"role_name": {
+				Description: `Name of the IAM role created when role_arn is not set. An existing role with this name is adopted.`,
+				Type:        schema.TypeString,
+				Optional:    true,
+				ForceNew:    true,
+				Default:     accountLoggingDefaultRoleName,
+			},
+			"tags": tftags.TagsSchema(),
+			"managed_role_arn": {
+				Description: `ARN of the IAM role created or adopted by this resource, set back on the account when changed outside of Terraform.`,
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"role_created": {
+				Description: `Whether the IAM role was created by this resource, in which case it is deleted on destroy.`,
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"policy_attached": {
+				Description: `Whether the AmazonAPIGatewayPushToCloudWatchLogs policy was attached to the IAM role by this resource, in which case it is detached on destroy.`,
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"previous_role_arn": {
+				Description: `CloudWatch Logs role ARN of the account before this resource was created.`,
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func resourceApiGatewayAccountLoggingRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).APIGatewayConn
+	account, err := conn.GetAccount(&apigateway.GetAccountInput{})
+	if err != nil {
+		return fmt.Errorf("reading API Gateway account: %w", err)
+	}
+
+	// Roles created before managed_role_arn was recorded.
+	if d.Get("managed_role_arn").(string) == "" && d.Get("role_created").(bool) {
+		roleName := d.Get("role_name").(string)
+		output, err := meta.(*conns.AWSClient).IAMConn.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return fmt.Errorf("reading IAM Role (%s): %w", roleName, err)
+		}
+		d.Set("managed_role_arn", output.Role.Arn)
+	}
+
+	// A different role set outside of Terraform shows up as a change of a
+	// configured role_arn, or of the managed role by
+	// resourceApiGatewayAccountLoggingRoleDiff.
+	d.Set("role_arn", account.CloudwatchRoleArn)
+	return nil
+}
+
+// resourceApiGatewayAccountLoggingRoleDiff plans setting the managed role back
+// on the account when it was changed outside of Terraform. role_arn is
+// computed then, its refreshed value would not show a diff otherwise.
+func resourceApiGatewayAccountLoggingRoleDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	managedRoleArn := d.Get("managed_role_arn").(string)
+	rawConfig := d.GetRawConfig()
+	if managedRoleArn == "" || rawConfig.IsNull() || !rawConfig.GetAttr("role_arn").IsNull() {
+		return nil
+	}
+	if d.Get("role_arn").(string) != managedRoleArn {
+		return d.SetNew("role_arn", managedRoleArn)
+	}
+	return nil
+}
+
+func resourceApiGatewayAccountLoggingCreate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).APIGatewayConn
+	account, err := conn.GetAccount(&apigateway.GetAccountInput{})
+	if err != nil {
+		return fmt.Errorf("reading API Gateway account: %w", err)
+	}
+	d.Set("previous_role_arn", account.CloudwatchRoleArn)
+
+	// Nothing uses the role until the account does, a failed create undoes
+	// it so the next apply starts over instead of adopting it.
+	roleArn := d.Get("role_arn").(string)
+	if roleArn == "" {
+		arn, err := ensureAccountLoggingRole(d, meta)
+		if err != nil {
+			return undoAccountLoggingRole(d, meta, err)
+		}
+		roleArn = arn
+		d.Set("managed_role_arn", roleArn)
+	}
+
+	if err := updateAccountCloudwatchRoleArn(conn, roleArn); err != nil {
+		return undoAccountLoggingRole(d, meta, err)
+	}
+
+	d.SetId(meta.(*conns.AWSClient).Region)
+	return resourceApiGatewayAccountLoggingRead(d, meta)
+}
+
+func resourceApiGatewayAccountLoggingUpdate(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).APIGatewayConn
+	if d.HasChange("tags") && d.Get("role_created").(bool) {
+		o, n := d.GetChange("tags")
+		if err := updateAccountLoggingRoleTags(d, meta, o.(map[string]interface{}), n.(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	if d.HasChange("role_arn") {
+		if err := updateAccountCloudwatchRoleArn(conn, d.Get("role_arn").(string)); err != nil {
+			return err
+		}
+	}
+	return resourceApiGatewayAccountLoggingRead(d, meta)
+}
+
+func resourceApiGatewayAccountLoggingDelete(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).APIGatewayConn
+	if err := updateAccountCloudwatchRoleArn(conn, d.Get("previous_role_arn").(string)); err != nil {
+		return err
+	}
+
+	return releaseAccountLoggingRole(d, meta)
+}
+
+// undoAccountLoggingRole releases the role after a failed create and returns
+// the error of the create along with any of the release.
+func undoAccountLoggingRole(d *schema.ResourceData, meta interface{}, err error) error {
+	if releaseErr := releaseAccountLoggingRole(d, meta); releaseErr != nil {
+		return multierror.Append(err, releaseErr)
+	}
+	return err
+}
+
+// releaseAccountLoggingRole detaches the push-to-CloudWatch policy when the
+// resource attached it, and deletes the role when the resource created it.
+// Roles created before policy_attached was recorded always had it attached.
+func releaseAccountLoggingRole(d *schema.ResourceData, meta interface{}) error {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	roleName := d.Get("role_name").(string)
+
+	if d.Get("policy_attached").(bool) || d.Get("role_created").(bool) {
+		_, err := iamConn.DetachRolePolicy(&iam.DetachRolePolicyInput{
+			RoleName:  aws.String(roleName),
+			PolicyArn: aws.String(accountLoggingPolicyARN(meta)),
+		})
+		if err != nil && !tfawserr.ErrCodeEquals(err, iam.ErrCodeNoSuchEntityException) {
+			return fmt.Errorf("detaching IAM Role (%s) policy: %w", roleName, err)
+		}
+		d.Set("policy_attached", false)
+	}
+
+	if !d.Get("role_created").(bool) {
+		return nil
+	}
+
+	log.Printf("[DEBUG] Deleting IAM Role: %s", roleName)
+	_, err := iamConn.DeleteRole(&iam.DeleteRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, iam.ErrCodeNoSuchEntityException) {
+		return fmt.Errorf("deleting IAM Role (%s): %w", roleName, err)
+	}
+	d.Set("role_created", false)
+	return nil
+}
+
+func accountLoggingPolicyARN(meta interface{}) string {
+	return fmt.Sprintf("arn:%s:%s", meta.(*conns.AWSClient).Partition, accountLoggingPolicyARNSuffix)
+}
+
+// ensureAccountLoggingRole creates the IAM role, or adopts an existing role
+// with the same name, and attaches the push-to-CloudWatch policy to it unless
+// it already has it. role_created and policy_attached record what was done
+// as it is done, so a failure can be undone.
+func ensureAccountLoggingRole(d *schema.ResourceData, meta interface{}) (string, error) {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	defaultTagsConfig := meta.(*conns.AWSClient).DefaultTagsConfig
+	tags := defaultTagsConfig.MergeTags(tftags.New(d.Get("tags").(map[string]interface{}))).IgnoreAWS()
+	roleName := d.Get("role_name").(string)
+	policyArn := accountLoggingPolicyARN(meta)
+
+	input := &iam.CreateRoleInput{
+		RoleName:                 aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(accountLoggingAssumeRolePolicy),
+		Description:              aws.String("Allows API Gateway to push logs to CloudWatch Logs."),
+	}
+	for k, v := range tags.Map() {
+		input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	var role *iam.Role
+	output, err := iamConn.CreateRole(input)
+	if tfawserr.ErrCodeEquals(err, iam.ErrCodeEntityAlreadyExistsException) {
+		existing, err := iamConn.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return "", fmt.Errorf("reading IAM Role (%s): %w", roleName, err)
+		}
+		role = existing.Role
+
+		attached, err := roleHasPolicy(iamConn, roleName, policyArn)
+		if err != nil {
+			return "", fmt.Errorf("reading IAM Role (%s) policies: %w", roleName, err)
+		}
+		if attached {
+			return aws.StringValue(role.Arn), nil
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("creating IAM Role (%s): %w", roleName, err)
+	} else {
+		role = output.Role
+		d.Set("role_created", true)
+	}
+
+	_, err = iamConn.AttachRolePolicy(&iam.AttachRolePolicyInput{
+		RoleName:  aws.String(roleName),
+		PolicyArn: aws.String(policyArn),
+	})
+	if err != nil {
+		return "", fmt.Errorf("attaching policy to IAM Role (%s): %w", roleName, err)
+	}
+	d.Set("policy_attached", true)
+
+	return aws.StringValue(role.Arn), nil
+}
+
+// updateAccountLoggingRoleTags updates the tags of the role created by the
+// resource in place, the account keeps using it.
+func updateAccountLoggingRoleTags(d *schema.ResourceData, meta interface{}, o, n map[string]interface{}) error {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	defaultTagsConfig := meta.(*conns.AWSClient).DefaultTagsConfig
+	roleName := d.Get("role_name").(string)
+	oldTags := defaultTagsConfig.MergeTags(tftags.New(o)).IgnoreAWS()
+	newTags := defaultTagsConfig.MergeTags(tftags.New(n)).IgnoreAWS()
+
+	if removed := oldTags.Removed(newTags); len(removed) > 0 {
+		_, err := iamConn.UntagRole(&iam.UntagRoleInput{
+			RoleName: aws.String(roleName),
+			TagKeys:  aws.StringSlice(removed.Keys()),
+		})
+		if err != nil {
+			return fmt.Errorf("untagging IAM Role (%s): %w", roleName, err)
+		}
+	}
+	if updated := oldTags.Updated(newTags); len(updated) > 0 {
+		input := &iam.TagRoleInput{
+			RoleName: aws.String(roleName),
+		}
+		for k, v := range updated.Map() {
+			input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		if _, err := iamConn.TagRole(input); err != nil {
+			return fmt.Errorf("tagging IAM Role (%s): %w", roleName, err)
+		}
+	}
+	return nil
+}
+
+func roleHasPolicy(conn *iam.IAM, roleName string, policyArn string) (bool, error) {
+	found := false
+	err := conn.ListAttachedRolePoliciesPages(&iam.ListAttachedRolePoliciesInput{
+		RoleName: aws.String(roleName),
+	}, func(page *iam.ListAttachedRolePoliciesOutput, lastPage bool) bool {
+		if page == nil {
+			return !lastPage
+		}
+
+		for _, policy := range page.AttachedPolicies {
+			if aws.StringValue(policy.PolicyArn) == policyArn {
+				found = true
+				return false
+			}
+		}
+
+		return !lastPage
+	})
+	return found, err
+}
+
+func updateAccountCloudwatchRoleArn(conn *apigateway.APIGateway, roleArn string) error {
+	input := &apigateway.UpdateAccountInput{
+		PatchOperations: []*apigateway.PatchOperation{
+			{
+				Op:    aws.String("replace"),
+				Path:  aws.String("/cloudwatchRoleArn"),
+				Value: aws.String(roleArn),
+			},
+		},
+	}
+
+	// A new role takes a while to be assumable by API Gateway.
+	_, err := tfresource.RetryWhenAWSErrMessageContains(accountLoggingPropagation, func() (interface{}, error) {
+		return conn.UpdateAccount(input)
+	}, apigateway.ErrCodeBadRequestException, "The role ARN does not have required permissions")
+
+	if err != nil {
+		return fmt.Errorf("updating API Gateway account CloudWatch role (%s): %w", roleArn, err)
+	}
+	return nil
+}
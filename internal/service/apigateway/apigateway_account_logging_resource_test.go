@@ -0,0 +1,250 @@
+package apigateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+)
+
+// fakeAccountLogging serves the IAM role operations and the API Gateway
+// account, and records the calls changing them. roleExists makes CreateRole
+// fail as the role already exists, policyAttached lists the managed policy
+// on it, updateFails denies the account update. cloudwatchRoleArn is the
+// role of the account.
+type fakeAccountLogging struct {
+	roleExists        bool
+	policyAttached    bool
+	updateFails       bool
+	cloudwatchRoleArn string
+
+	calls []string
+}
+
+func (f *fakeAccountLogging) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/account" {
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, `{"cloudwatchRoleArn":%q}`, f.cloudwatchRoleArn)
+			return
+		}
+		f.calls = append(f.calls, "UpdateAccount")
+		if f.updateFails {
+			w.Header().Set("X-Amzn-Errortype", apigateway.ErrCodeBadRequestException)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message":"CloudWatch Logs role ARN must be set in account settings"}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+		return
+	}
+
+	r.ParseForm()
+	action := r.Form.Get("Action")
+	switch action {
+	case "CreateRole":
+		f.calls = append(f.calls, action)
+		if f.roleExists {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>Role exists</Message></Error></ErrorResponse>`, iam.ErrCodeEntityAlreadyExistsException)
+			return
+		}
+		fmt.Fprint(w, `<CreateRoleResponse><CreateRoleResult><Role><Arn>arn:aws:iam::123456789012:role/noname</Arn></Role></CreateRoleResult></CreateRoleResponse>`)
+	case "GetRole":
+		fmt.Fprint(w, `<GetRoleResponse><GetRoleResult><Role><Arn>arn:aws:iam::123456789012:role/noname</Arn></Role></GetRoleResult></GetRoleResponse>`)
+	case "ListAttachedRolePolicies":
+		policies := ""
+		if f.policyAttached {
+			policies = fmt.Sprintf(`<member><PolicyArn>arn:aws:%s</PolicyArn></member>`, accountLoggingPolicyARNSuffix)
+		}
+		fmt.Fprintf(w, `<ListAttachedRolePoliciesResponse><ListAttachedRolePoliciesResult><AttachedPolicies>%s</AttachedPolicies><IsTruncated>false</IsTruncated></ListAttachedRolePoliciesResult></ListAttachedRolePoliciesResponse>`, policies)
+	default:
+		f.calls = append(f.calls, action)
+		fmt.Fprintf(w, `<%[1]sResponse></%[1]sResponse>`, action)
+	}
+}
+
+func testAccountLoggingClient(t *testing.T, fake *fakeAccountLogging) *conns.AWSClient {
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	return &conns.AWSClient{
+		APIGatewayConn: apigateway.New(sess),
+		IAMConn:        iam.New(sess),
+		Partition:      "aws",
+		Region:         "us-east-1", //lintignore:AWSAT003
+	}
+}
+
+func TestResourceApiGatewayAccountLogging_role(t *testing.T) {
+	testCases := []struct {
+		name           string
+		fake           *fakeAccountLogging
+		expectErr      bool
+		expectedCreate []string
+		expectedDelete []string
+	}{
+		{
+			name:           "created role",
+			fake:           &fakeAccountLogging{},
+			expectedCreate: []string{"CreateRole", "AttachRolePolicy", "UpdateAccount"},
+			expectedDelete: []string{"UpdateAccount", "DetachRolePolicy", "DeleteRole"},
+		},
+		{
+			name:           "created role failing update",
+			fake:           &fakeAccountLogging{updateFails: true},
+			expectErr:      true,
+			expectedCreate: []string{"CreateRole", "AttachRolePolicy", "UpdateAccount", "DetachRolePolicy", "DeleteRole"},
+		},
+		{
+			name:           "adopted role",
+			fake:           &fakeAccountLogging{roleExists: true},
+			expectedCreate: []string{"CreateRole", "AttachRolePolicy", "UpdateAccount"},
+			expectedDelete: []string{"UpdateAccount", "DetachRolePolicy"},
+		},
+		{
+			name:           "adopted role with the policy",
+			fake:           &fakeAccountLogging{roleExists: true, policyAttached: true},
+			expectedCreate: []string{"CreateRole", "UpdateAccount"},
+			expectedDelete: []string{"UpdateAccount"},
+		},
+		{
+			name:           "adopted role failing update",
+			fake:           &fakeAccountLogging{roleExists: true, updateFails: true},
+			expectErr:      true,
+			expectedCreate: []string{"CreateRole", "AttachRolePolicy", "UpdateAccount", "DetachRolePolicy"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := testAccountLoggingClient(t, tc.fake)
+			d := schema.TestResourceDataRaw(t, ResourceApiGatewayAccountLogging().Schema, map[string]interface{}{})
+
+			err := resourceApiGatewayAccountLoggingCreate(d, meta)
+			if tc.expectErr != (err != nil) {
+				t.Fatalf("got error %v, expected error: %t", err, tc.expectErr)
+			}
+			if !reflect.DeepEqual(tc.fake.calls, tc.expectedCreate) {
+				t.Errorf("create: got %v, expected %v", tc.fake.calls, tc.expectedCreate)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			tc.fake.calls = nil
+			if err := resourceApiGatewayAccountLoggingDelete(d, meta); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(tc.fake.calls, tc.expectedDelete) {
+				t.Errorf("delete: got %v, expected %v", tc.fake.calls, tc.expectedDelete)
+			}
+		})
+	}
+}
+
+func TestResourceApiGatewayAccountLogging_tags(t *testing.T) {
+	fake := &fakeAccountLogging{}
+	meta := testAccountLoggingClient(t, fake)
+	d := schema.TestResourceDataRaw(t, ResourceApiGatewayAccountLogging().Schema, map[string]interface{}{
+		"tags": map[string]interface{}{"team": "api", "env": "dev"},
+	})
+	if err := resourceApiGatewayAccountLoggingCreate(d, meta); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := "arn:aws:iam::123456789012:role/noname"; d.Get("managed_role_arn").(string) != expected { //lintignore:AWSAT005
+		t.Errorf("managed_role_arn: got %s, expected %s", d.Get("managed_role_arn"), expected)
+	}
+
+	fake.calls = nil
+	if err := updateAccountLoggingRoleTags(d, meta, map[string]interface{}{"team": "api", "env": "dev"}, map[string]interface{}{"team": "platform"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := []string{"UntagRole", "TagRole"}; !reflect.DeepEqual(fake.calls, expected) {
+		t.Errorf("got %v, expected %v", fake.calls, expected)
+	}
+}
+
+func TestResourceApiGatewayAccountLogging_roleDrift(t *testing.T) {
+	managedRoleArn := "arn:aws:iam::123456789012:role/noname"     //lintignore:AWSAT005
+	otherRoleArn := "arn:aws:iam::123456789012:role/someone-else" //lintignore:AWSAT005
+
+	testCases := []struct {
+		name     string
+		config   map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "managed role",
+			config:   map[string]interface{}{},
+			expected: managedRoleArn,
+		},
+		{
+			name:     "configured role",
+			config:   map[string]interface{}{"role_arn": otherRoleArn},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := ResourceApiGatewayAccountLogging()
+			state := &terraform.InstanceState{
+				ID: "us-east-1", //lintignore:AWSAT003
+				Attributes: map[string]string{
+					"id":                "us-east-1", //lintignore:AWSAT003
+					"role_arn":          otherRoleArn,
+					"role_name":         accountLoggingDefaultRoleName,
+					"managed_role_arn":  managedRoleArn,
+					"role_created":      "true",
+					"policy_attached":   "true",
+					"previous_role_arn": "",
+				},
+			}
+
+			config := testAccountLoggingConfig(r, tc.config)
+			state.RawConfig = config
+			diff, err := r.Diff(context.Background(), state, terraform.NewResourceConfigShimmed(config, r.CoreConfigSchema()), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			actual := ""
+			if diff != nil && diff.Attributes["role_arn"] != nil {
+				actual = diff.Attributes["role_arn"].New
+			}
+			if actual != tc.expected {
+				t.Errorf("planned role_arn: got %q, expected %q", actual, tc.expected)
+			}
+		})
+	}
+}
+
+// testAccountLoggingConfig returns the configuration with the string
+// attributes of raw, the other attributes null.
+func testAccountLoggingConfig(r *schema.Resource, raw map[string]interface{}) cty.Value {
+	attrs := make(map[string]cty.Value)
+	for name, attrType := range r.CoreConfigSchema().ImpliedType().AttributeTypes() {
+		attrs[name] = cty.NullVal(attrType)
+	}
+	for name, v := range raw {
+		attrs[name] = cty.StringVal(v.(string))
+	}
+	return cty.ObjectVal(attrs)
+}
@@ -1,7 +1,9 @@
 package apigateway
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/apigateway"
@@ -11,39 +13,260 @@ import (
 
 func DataSourceApiGateway() *schema.Resource {
 	return &schema.Resource{
-		Description: `Use this data source to get the access to the effective
-		Account ID, User ID, ARN and EKS Role ARN in which Terraform is authorized.`,
-		Read: dataSourceApiGatewayRed,
+		Description: `Use this data source to get the settings of an API Gateway REST API of the region and of its stages.`,
+		Read:        dataSourceApiGatewayRead,
 		Schema: map[string]*schema.Schema{
 			"rest_api_id": {
-				Description: `AWS Account ID number of the account that owns or contains the calling entity.`,
+				Description: `ID of the REST API.`,
 				Type:        schema.TypeString,
 				Required:    true,
 			},
+			"name": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"endpoint_configuration": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"types": {
+							Description: "Endpoint types of the REST API, `EDGE`, `REGIONAL` or `PRIVATE`.",
+							Type:        schema.TypeList,
+							Elem:        &schema.Schema{Type: schema.TypeString},
+							Computed:    true,
+						},
+						"vpc_endpoint_ids": {
+							Type:     schema.TypeList,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
+						},
+					},
+				},
+			},
+			"policy": {
+				Description: `JSON resource policy of the REST API.`,
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"api_key_source": {
+				Description: "Source of the API keys of usage plans, `HEADER` or `AUTHORIZER`.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"binary_media_types": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
+			"tags": {
+				Type:     schema.TypeMap,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				Computed: true,
+			},
 			"stages": {
 				Description: `List of stages of the API`,
 				Type:        schema.TypeList,
 				Elem:        &schema.Schema{Type: schema.TypeString},
 				Computed:    true,
 			},
+			"stage_details": {
+				Description: `Settings of the stages of the API, in the order of stages.`,
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"stage_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"deployment_id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"cache_cluster_enabled": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"cache_cluster_size": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"cache_cluster_status": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"variables": {
+							Type:     schema.TypeMap,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
+						},
+						"access_log_settings": {
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"destination_arn": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"format": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+								},
+							},
+						},
+						"method_settings": {
+							Description: "Method settings of the stage, sorted by method path, `*/*` for all methods.",
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"method_path": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"logging_level": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"data_trace_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"metrics_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"caching_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"throttling_burst_limit": {
+										Type:     schema.TypeInt,
+										Computed: true,
+									},
+									"throttling_rate_limit": {
+										Type:     schema.TypeFloat,
+										Computed: true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
-func dataSourceApiGatewayRed(d *schema.ResourceData, meta interface{}) error {
+func dataSourceApiGatewayRead(d *schema.ResourceData, meta interface{}) error {
 	client := meta.(*conns.AWSClient).APIGatewayConn
 	restApiId := d.Get("rest_api_id").(string)
-	res, err := client.GetStages(&apigateway.GetStagesInput{RestApiId: &restApiId})
+	restApi, err := client.GetRestApi(&apigateway.GetRestApiInput{RestApiId: aws.String(restApiId)})
+	if err != nil {
+		return fmt.Errorf("getting REST API (%s): %w", restApiId, err)
+	}
+	res, err := client.GetStages(&apigateway.GetStagesInput{RestApiId: aws.String(restApiId)})
 	if err != nil {
 		return fmt.Errorf("getting REST API Stages: %w", err)
 	}
+	policy, err := flattenRestApiPolicy(restApi.Policy)
+	if err != nil {
+		return fmt.Errorf("reading REST API (%s) policy: %w", restApiId, err)
+	}
+
 	stages := []string{}
+	stageDetails := []interface{}{}
 	for _, stage := range res.Item {
-		stages = append(stages, *stage.StageName)
+		stages = append(stages, aws.StringValue(stage.StageName))
+		stageDetails = append(stageDetails, flattenStageDetails(stage))
 	}
 
-	d.SetId(*aws.String(restApiId))
-	d.Set("rest_api_id", aws.String(restApiId))
-	d.Set("stages", aws.StringSlice(stages))
+	d.SetId(restApiId)
+	d.Set("rest_api_id", restApiId)
+	d.Set("name", restApi.Name)
+	d.Set("description", restApi.Description)
+	d.Set("policy", policy)
+	d.Set("api_key_source", restApi.ApiKeySource)
+	d.Set("binary_media_types", aws.StringValueSlice(restApi.BinaryMediaTypes))
+	d.Set("tags", aws.StringValueMap(restApi.Tags))
+	if err := d.Set("endpoint_configuration", flattenEndpointConfiguration(restApi.EndpointConfiguration)); err != nil {
+		return fmt.Errorf("setting endpoint_configuration: %w", err)
+	}
+	d.Set("stages", stages)
+	if err := d.Set("stage_details", stageDetails); err != nil {
+		return fmt.Errorf("setting stage_details: %w", err)
+	}
 	return nil
 }
+
+// flattenRestApiPolicy returns the policy of the REST API, which API Gateway
+// returns JSON escaped, its quotes and slashes included.
+func flattenRestApiPolicy(policy *string) (string, error) {
+	v := aws.StringValue(policy)
+	if v == "" {
+		return "", nil
+	}
+
+	var unescaped string
+	if err := json.Unmarshal([]byte(`"`+v+`"`), &unescaped); err != nil {
+		return "", err
+	}
+	return unescaped, nil
+}
+
+func flattenEndpointConfiguration(config *apigateway.EndpointConfiguration) []interface{} {
+	if config == nil {
+		return []interface{}{}
+	}
+	return []interface{}{map[string]interface{}{
+		"types":            aws.StringValueSlice(config.Types),
+		"vpc_endpoint_ids": aws.StringValueSlice(config.VpcEndpointIds),
+	}}
+}
+
+func flattenStageDetails(stage *apigateway.Stage) map[string]interface{} {
+	accessLogSettings := []interface{}{}
+	if settings := stage.AccessLogSettings; settings != nil {
+		accessLogSettings = append(accessLogSettings, map[string]interface{}{
+			"destination_arn": aws.StringValue(settings.DestinationArn),
+			"format":          aws.StringValue(settings.Format),
+		})
+	}
+
+	methodPaths := make([]string, 0, len(stage.MethodSettings))
+	for methodPath := range stage.MethodSettings {
+		methodPaths = append(methodPaths, methodPath)
+	}
+	sort.Strings(methodPaths)
+	methodSettings := []interface{}{}
+	for _, methodPath := range methodPaths {
+		settings := stage.MethodSettings[methodPath]
+		methodSettings = append(methodSettings, map[string]interface{}{
+			"method_path":            methodPath,
+			"logging_level":          aws.StringValue(settings.LoggingLevel),
+			"data_trace_enabled":     aws.BoolValue(settings.DataTraceEnabled),
+			"metrics_enabled":        aws.BoolValue(settings.MetricsEnabled),
+			"caching_enabled":        aws.BoolValue(settings.CachingEnabled),
+			"throttling_burst_limit": int(aws.Int64Value(settings.ThrottlingBurstLimit)),
+			"throttling_rate_limit":  aws.Float64Value(settings.ThrottlingRateLimit),
+		})
+	}
+
+	return map[string]interface{}{
+		"stage_name":            aws.StringValue(stage.StageName),
+		"deployment_id":         aws.StringValue(stage.DeploymentId),
+		"cache_cluster_enabled": aws.BoolValue(stage.CacheClusterEnabled),
+		"cache_cluster_size":    aws.StringValue(stage.CacheClusterSize),
+		"cache_cluster_status":  aws.StringValue(stage.CacheClusterStatus),
+		"variables":             aws.StringValueMap(stage.Variables),
+		"access_log_settings":   accessLogSettings,
+		"method_settings":       methodSettings,
+	}
+}
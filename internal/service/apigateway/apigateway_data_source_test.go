@@ -0,0 +1,80 @@
+package apigateway
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	apigatewayintegration "github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway-integration"
+)
+
+func TestFlattenRestApiPolicy(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   *string
+		expected string
+	}{
+		{
+			name:     "no policy",
+			expected: "",
+		},
+		{
+			name:     "escaped policy",
+			policy:   aws.String(`{\"Version\":\"2012-10-17\",\"Statement\":[{\"Effect\":\"Allow\",\"Principal\":\"*\",\"Action\":\"execute-api:Invoke\",\"Resource\":\"*\"}]}`),
+			expected: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"execute-api:Invoke","Resource":"*"}]}`,
+		},
+		{
+			name:     "escaped slashes",
+			policy:   aws.String(`{\"Version\":\"2012-10-17\",\"Statement\":[{\"Effect\":\"Allow\",\"Principal\":\"*\",\"Action\":\"execute-api:Invoke\",\"Resource\":\"arn:aws:execute-api:us-east-1:123456789012:a1b2c3d4e5\/*\"}]}`), //lintignore:AWSAT003,AWSAT005
+			expected: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"execute-api:Invoke","Resource":"arn:aws:execute-api:us-east-1:123456789012:a1b2c3d4e5/*"}]}`,                                    //lintignore:AWSAT003,AWSAT005
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := flattenRestApiPolicy(tc.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != tc.expected {
+				t.Errorf("got %s, expected %s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFlattenStageDetails(t *testing.T) {
+	stage := &apigateway.Stage{
+		StageName:    aws.String("prod"),
+		DeploymentId: aws.String("abc123"),
+		Variables:    aws.StringMap(map[string]string{"backend": "prod.internal"}),
+		MethodSettings: map[string]*apigateway.MethodSetting{
+			"pets/GET": {
+				LoggingLevel:        aws.String("ERROR"),
+				ThrottlingRateLimit: aws.Float64(100),
+			},
+			apigatewayintegration.WildcardMethodPath: {
+				LoggingLevel:         aws.String("INFO"),
+				MetricsEnabled:       aws.Bool(true),
+				ThrottlingBurstLimit: aws.Int64(5000),
+			},
+		},
+	}
+
+	actual := flattenStageDetails(stage)
+
+	methodPaths := []string{}
+	for _, tfMap := range actual["method_settings"].([]interface{}) {
+		methodPaths = append(methodPaths, tfMap.(map[string]interface{})["method_path"].(string))
+	}
+	if expected := []string{apigatewayintegration.WildcardMethodPath, "pets/GET"}; !reflect.DeepEqual(methodPaths, expected) {
+		t.Errorf("got method paths %v, expected %v", methodPaths, expected)
+	}
+	if wildcard := actual["method_settings"].([]interface{})[0].(map[string]interface{}); wildcard["throttling_burst_limit"] != 5000 {
+		t.Errorf("got throttling_burst_limit %v, expected 5000", wildcard["throttling_burst_limit"])
+	}
+	if accessLogSettings := actual["access_log_settings"].([]interface{}); len(accessLogSettings) != 0 {
+		t.Errorf("got access_log_settings %v, expected none", accessLogSettings)
+	}
+}
@@ -0,0 +1,149 @@
+package apigateway
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	"github.com/idanhaitner/terraform-provider-noname/internal/flex"
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+const (
+	exportTypeOAS30   = "oas30"
+	exportTypeSwagger = "swagger"
+)
+
+func exportType_Values() []string {
+	return []string{exportTypeOAS30, exportTypeSwagger}
+}
+
+const (
+	exportFormatJSON = "json"
+	exportFormatYAML = "yaml"
+)
+
+func exportFormat_Values() []string {
+	return []string{exportFormatJSON, exportFormatYAML}
+}
+
+func exportExtension_Values() []string {
+	return []string{"integrations", "authorizers", "postman"}
+}
+
+func DataSourceApiGatewayExport() *schema.Resource {
+	return &schema.Resource{
+		Description: `Use this data source to export the OpenAPI definition of a deployed API Gateway REST API stage,
+such as for API discovery in Noname.`,
+		Read: dataSourceApiGatewayExportRead,
+		Schema: map[string]*schema.Schema{
+			"rest_api_id": {
+				Description: `ID of the REST API.`,
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"stage_name": {
+				Description: `Name of the stage to export.`,
+				Type:        schema.TypeString,
+				Required:    true,
+			},
+			"export_type": {
+				Description:  "`oas30` for OpenAPI 3.0 or `swagger` for OpenAPI 2.0.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      exportTypeOAS30,
+				ValidateFunc: validation.StringInSlice(exportType_Values(), false),
+			},
+			"format": {
+				Description:  "`json` or `yaml`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      exportFormatJSON,
+				ValidateFunc: validation.StringInSlice(exportFormat_Values(), false),
+			},
+			"extensions": {
+				Description: "Extensions included in the export, any of `integrations`, `authorizers` and `postman`.",
+				Type:        schema.TypeSet,
+				Optional:    true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.StringInSlice(exportExtension_Values(), false),
+				},
+			},
+			"body": {
+				Description: `Exported definition, normalized so it only changes with the API.`,
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"content_type": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"content_sha256": {
+				Description: `Hex encoded SHA-256 hash of body, to trigger uploads of changed definitions.`,
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceApiGatewayExportRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*conns.AWSClient).APIGatewayConn
+	restApiId := d.Get("rest_api_id").(string)
+	stageName := d.Get("stage_name").(string)
+	exportType := d.Get("export_type").(string)
+
+	input := &apigateway.GetExportInput{
+		RestApiId:  aws.String(restApiId),
+		StageName:  aws.String(stageName),
+		ExportType: aws.String(exportType),
+		Accepts:    aws.String(exportAccepts(d.Get("format").(string))),
+	}
+	if extensions := aws.StringValueSlice(flex.ExpandStringSet(d.Get("extensions").(*schema.Set))); len(extensions) > 0 {
+		input.Parameters = aws.StringMap(map[string]string{"extensions": exportExtensions(extensions)})
+	}
+
+	output, err := client.GetExport(input)
+	if err != nil {
+		return fmt.Errorf("exporting REST API (%s) stage (%s): %w", restApiId, stageName, err)
+	}
+
+	body, err := verify.NormalizeJSONOrYAMLString(string(output.Body))
+	if err != nil {
+		return fmt.Errorf("reading REST API (%s) stage (%s) export: %w", restApiId, stageName, err)
+	}
+
+	d.SetId(fmt.Sprintf("%s/%s/%s", restApiId, stageName, exportType))
+	d.Set("body", body)
+	d.Set("content_type", output.ContentType)
+	d.Set("content_sha256", exportContentSha256(body))
+	return nil
+}
+
+func exportAccepts(format string) string {
+	if format == exportFormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// exportExtensions returns the extensions export parameter, sorted so the
+// request only changes with the configuration.
+func exportExtensions(extensions []string) string {
+	sorted := append([]string{}, extensions...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func exportContentSha256(body string) string {
+	hash := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(hash[:])
+}
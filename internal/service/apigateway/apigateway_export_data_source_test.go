@@ -0,0 +1,53 @@
+package apigateway
+
+import (
+	"testing"
+
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+func TestExportExtensions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		extensions []string
+		expected   string
+	}{
+		{
+			name:       "single",
+			extensions: []string{"postman"},
+			expected:   "postman",
+		},
+		{
+			name:       "sorted",
+			extensions: []string{"integrations", "authorizers"},
+			expected:   "authorizers,integrations",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := exportExtensions(tc.extensions)
+			if actual != tc.expected {
+				t.Errorf("got %s, expected %s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestExportContentSha256(t *testing.T) {
+	compact, err := verify.NormalizeJSONOrYAMLString(`{"openapi":"3.0.1","info":{"title":"pets","version":"1"}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	indented, err := verify.NormalizeJSONOrYAMLString("{\n  \"info\": {\n    \"version\": \"1\",\n    \"title\": \"pets\"\n  },\n  \"openapi\": \"3.0.1\"\n}\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if exportContentSha256(compact) != exportContentSha256(indented) {
+		t.Errorf("got different hashes for the same normalized definition: %s, %s", compact, indented)
+	}
+	if exportContentSha256(compact) == exportContentSha256("") {
+		t.Error("got the same hash for different definitions")
+	}
+}
@@ -0,0 +1,237 @@
+package apigateway
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	apigatewayintegration "github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway-integration"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+)
+
+const inventoryThrottleTimeout = 5 * time.Minute
+
+func DataSourceApiGatewayInventory() *schema.Resource {
+	return &schema.Resource{
+		Description: `Use this data source to list every REST API of the region with the logging settings of its stages,
+and whether each stage matches the Noname logging baseline: INFO logging and access logs including $context.requestId.`,
+		Read: dataSourceApiGatewayInventoryRead,
+		Schema: map[string]*schema.Schema{
+			"rest_apis": {
+				Description: `REST APIs of the region, sorted by ID.`,
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"tags": {
+							Type:     schema.TypeMap,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+							Computed: true,
+						},
+						"stages": {
+							Description: `Stages of the REST API, sorted by name.`,
+							Type:        schema.TypeList,
+							Computed:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"stage_name": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"deployment_id": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"logging_level": {
+										Description: "Logging level of the `*/*` method settings, `OFF` when the stage has none.",
+										Type:        schema.TypeString,
+										Computed:    true,
+									},
+									"data_trace_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"metrics_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"access_log_destination_arn": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"access_log_format": {
+										Type:     schema.TypeString,
+										Computed: true,
+									},
+									"xray_tracing_enabled": {
+										Type:     schema.TypeBool,
+										Computed: true,
+									},
+									"web_acl_arn": {
+										Description: "ARN of the WAF web ACL associated with the stage.",
+										Type:        schema.TypeString,
+										Computed:    true,
+									},
+									"tags": {
+										Type:     schema.TypeMap,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+										Computed: true,
+									},
+									"noname_integration_id": {
+										Description: "ID of the noname_api_gateway_integration owning the stage, if any.",
+										Type:        schema.TypeString,
+										Computed:    true,
+									},
+									"noname_compliant": {
+										Description: "Whether the stage matches the Noname logging baseline.",
+										Type:        schema.TypeBool,
+										Computed:    true,
+									},
+									"noname_drift": {
+										Description: "Settings of the stage that do not match the Noname logging baseline.",
+										Type:        schema.TypeList,
+										Elem:        &schema.Schema{Type: schema.TypeString},
+										Computed:    true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"noncompliant_stages": {
+				Description: `Stages, as "<rest_api_id>-<stage>", not matching the Noname logging baseline.`,
+				Type:        schema.TypeList,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func dataSourceApiGatewayInventoryRead(d *schema.ResourceData, meta interface{}) error {
+	conn := meta.(*conns.AWSClient).APIGatewayConn
+
+	var restApis []*apigateway.RestApi
+	err := conn.GetRestApisPages(&apigateway.GetRestApisInput{}, func(page *apigateway.GetRestApisOutput, lastPage bool) bool {
+		if page == nil {
+			return !lastPage
+		}
+
+		restApis = append(restApis, page.Items...)
+
+		return !lastPage
+	})
+	if err != nil {
+		return fmt.Errorf("listing API Gateway REST APIs: %w", err)
+	}
+	sort.Slice(restApis, func(i, j int) bool {
+		return aws.StringValue(restApis[i].Id) < aws.StringValue(restApis[j].Id)
+	})
+
+	tfList := []interface{}{}
+	noncompliantStages := []string{}
+	for _, restApi := range restApis {
+		restApiId := aws.StringValue(restApi.Id)
+		// Every REST API of the region is read, large accounts get
+		// throttled.
+		outputRaw, err := tfresource.RetryWhenAWSErrCodeEquals(inventoryThrottleTimeout, func() (interface{}, error) {
+			return apigatewayintegration.FindStagesByRestAPIID(conn, restApiId)
+		}, apigateway.ErrCodeTooManyRequestsException)
+		if tfresource.NotFound(err) {
+			// Deleted since it was listed.
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading REST API (%s) stages: %w", restApiId, err)
+		}
+
+		stages := outputRaw.([]*apigateway.Stage)
+		sort.Slice(stages, func(i, j int) bool {
+			return aws.StringValue(stages[i].StageName) < aws.StringValue(stages[j].StageName)
+		})
+		tfStages := []interface{}{}
+		for _, stage := range stages {
+			tfMap := flattenInventoryStage(stage)
+			if !tfMap["noname_compliant"].(bool) {
+				noncompliantStages = append(noncompliantStages, fmt.Sprintf("%v-%v", restApiId, aws.StringValue(stage.StageName)))
+			}
+			tfStages = append(tfStages, tfMap)
+		}
+
+		tfList = append(tfList, map[string]interface{}{
+			"id":     restApiId,
+			"name":   aws.StringValue(restApi.Name),
+			"tags":   aws.StringValueMap(restApi.Tags),
+			"stages": tfStages,
+		})
+	}
+
+	d.SetId(meta.(*conns.AWSClient).Region)
+	if err := d.Set("rest_apis", tfList); err != nil {
+		return fmt.Errorf("setting rest_apis: %w", err)
+	}
+	d.Set("noncompliant_stages", noncompliantStages)
+	return nil
+}
+
+func flattenInventoryStage(stage *apigateway.Stage) map[string]interface{} {
+	tfMap := map[string]interface{}{
+		"stage_name":            aws.StringValue(stage.StageName),
+		"deployment_id":         aws.StringValue(stage.DeploymentId),
+		"logging_level":         "OFF",
+		"data_trace_enabled":    false,
+		"metrics_enabled":       false,
+		"xray_tracing_enabled":  aws.BoolValue(stage.TracingEnabled),
+		"web_acl_arn":           aws.StringValue(stage.WebAclArn),
+		"tags":                  aws.StringValueMap(stage.Tags),
+		"noname_integration_id": aws.StringValue(stage.Tags[apigatewayintegration.IntegrationIdTagKey]),
+	}
+	if settings := stage.MethodSettings[apigatewayintegration.WildcardMethodPath]; settings != nil {
+		tfMap["logging_level"] = aws.StringValue(settings.LoggingLevel)
+		tfMap["data_trace_enabled"] = aws.BoolValue(settings.DataTraceEnabled)
+		tfMap["metrics_enabled"] = aws.BoolValue(settings.MetricsEnabled)
+	}
+	if settings := stage.AccessLogSettings; settings != nil {
+		tfMap["access_log_destination_arn"] = aws.StringValue(settings.DestinationArn)
+		tfMap["access_log_format"] = aws.StringValue(settings.Format)
+	} else {
+		tfMap["access_log_destination_arn"] = ""
+		tfMap["access_log_format"] = ""
+	}
+
+	drift := nonameBaselineDrift(stage)
+	tfMap["noname_compliant"] = len(drift) == 0
+	tfMap["noname_drift"] = drift
+	return tfMap
+}
+
+// nonameBaselineDrift returns the settings of the stage that do not match the
+// Noname logging baseline, whatever integration applied it.
+func nonameBaselineDrift(stage *apigateway.Stage) []string {
+	drift := []string{}
+	settings := stage.MethodSettings[apigatewayintegration.WildcardMethodPath]
+	if settings == nil || aws.StringValue(settings.LoggingLevel) != apigatewayintegration.NonameLoggingLevel {
+		drift = append(drift, "loggingLevel")
+	}
+	if stage.AccessLogSettings == nil || aws.StringValue(stage.AccessLogSettings.DestinationArn) == "" {
+		drift = append(drift, "accessLogSettings.destinationArn")
+	}
+	if stage.AccessLogSettings == nil || !strings.Contains(aws.StringValue(stage.AccessLogSettings.Format), "$context.requestId") {
+		drift = append(drift, "accessLogSettings.format")
+	}
+	return drift
+}
@@ -0,0 +1,112 @@
+package apigateway
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	apigatewayintegration "github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway-integration"
+)
+
+func TestNonameBaselineDrift(t *testing.T) {
+	destinationArn := "arn:aws:logs:us-east-1:123456789012:log-group:access" //lintignore:AWSAT003,AWSAT005
+
+	testCases := []struct {
+		name     string
+		stage    *apigateway.Stage
+		expected []string
+	}{
+		{
+			name: "compliant",
+			stage: &apigateway.Stage{
+				MethodSettings: map[string]*apigateway.MethodSetting{
+					apigatewayintegration.WildcardMethodPath: {LoggingLevel: aws.String("INFO")},
+				},
+				AccessLogSettings: &apigateway.AccessLogSettings{
+					DestinationArn: aws.String(destinationArn),
+					Format:         aws.String(`{"requestId":"$context.requestId"}`),
+				},
+			},
+			expected: []string{},
+		},
+		{
+			name:     "no logging",
+			stage:    &apigateway.Stage{},
+			expected: []string{"loggingLevel", "accessLogSettings.destinationArn", "accessLogSettings.format"},
+		},
+		{
+			name: "errors only without request ID",
+			stage: &apigateway.Stage{
+				MethodSettings: map[string]*apigateway.MethodSetting{
+					apigatewayintegration.WildcardMethodPath: {LoggingLevel: aws.String("ERROR")},
+				},
+				AccessLogSettings: &apigateway.AccessLogSettings{
+					DestinationArn: aws.String(destinationArn),
+					Format:         aws.String("$context.identity.sourceIp"),
+				},
+			},
+			expected: []string{"loggingLevel", "accessLogSettings.format"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := nonameBaselineDrift(tc.stage)
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("got %v, expected %v", actual, tc.expected)
+			}
+		})
+	}
+}
+
+// The stages of every REST API are read, the first read of each is
+// throttled.
+func TestDataSourceApiGatewayInventoryRead_throttled(t *testing.T) {
+	throttled := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restapis":
+			fmt.Fprint(w, `{"item":[{"id":"f6g7h8i9j0","name":"orders"},{"id":"a1b2c3d4e5","name":"pets"}]}`)
+		case "/restapis/a1b2c3d4e5/stages", "/restapis/f6g7h8i9j0/stages":
+			if !throttled[r.URL.Path] {
+				throttled[r.URL.Path] = true
+				w.Header().Set("X-Amzn-Errortype", apigateway.ErrCodeTooManyRequestsException)
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"message":"Too Many Requests"}`)
+				return
+			}
+			fmt.Fprint(w, `{"item":[{"stageName":"prod"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(server.URL),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	meta := &conns.AWSClient{
+		APIGatewayConn: apigateway.New(sess),
+		Region:         "us-east-1", //lintignore:AWSAT003
+	}
+
+	d := schema.TestResourceDataRaw(t, DataSourceApiGatewayInventory().Schema, map[string]interface{}{})
+	if err := dataSourceApiGatewayInventoryRead(d, meta); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []interface{}{"a1b2c3d4e5-prod", "f6g7h8i9j0-prod"}
+	if actual := d.Get("noncompliant_stages").([]interface{}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("noncompliant stages: got %v, expected %v", actual, expected)
+	}
+}
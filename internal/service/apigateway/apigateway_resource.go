@@ -1,6 +1,9 @@
 package apigateway
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go/service/apigateway"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -15,6 +18,9 @@ func ResourceApiGateway() *schema.Resource {
 		Create: resourceApiGatewayCreate,
 		Delete: resourceApiGatewayDelete,
 		Update: resourceApiGatewayUpdate,
+		Importer: &schema.ResourceImporter{
+			State: resourceApiGatewayImport,
+		},
 		Schema: map[string]*schema.Schema{
 			"rest_api_id": {
 				Description: `AWS Account ID number of the account that owns or contains the calling entity.`,
@@ -50,6 +56,23 @@ func resourceApiGatewayRead(d *schema.ResourceData, meta interface{}) error {
 	return nil
 }
 
+// resourceApiGatewayImport adopts a "<rest_api_id>_<stage_name>" stage. Its current
+// description is kept as the one restored on destroy.
+func resourceApiGatewayImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	restApiId, stageName, ok := strings.Cut(d.Id(), "_")
+	if !ok || restApiId == "" || stageName == "" {
+		return nil, fmt.Errorf("unexpected format of ID (%s), expected <rest_api_id>_<stage_name>", d.Id())
+	}
+
+	d.Set("rest_api_id", restApiId)
+	d.Set("stage_name", stageName)
+	if err := saveCurrentDescriptionState(d, meta); err != nil {
+		return nil, fmt.Errorf("reading API Gateway REST API (%s) stage (%s): %w", restApiId, stageName, err)
+	}
+	d.Set("description", d.Get("current_description"))
+	return []*schema.ResourceData{d}, nil
+}
+
 func saveCurrentDescriptionState(d *schema.ResourceData, meta interface{}) error {
 	conn := meta.(*conns.AWSClient).APIGatewayConn
 	restApiId := d.Get("rest_api_id").(string)
@@ -0,0 +1,195 @@
+package firehose
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+)
+
+const deliveryRolePolicyName = "noname-firehose-delivery"
+
+// deliveryAssumeRolePolicy lets CloudWatch Logs use the role too, so it can
+// be the role_arn of a noname_log_forwarding to the delivery stream. Both
+// services only assume it on behalf of the account.
+func deliveryAssumeRolePolicy(accountId string) string {
+	return fmt.Sprintf(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {
+        "Service": [
+          "firehose.amazonaws.com",
+          "logs.amazonaws.com"
+        ]
+      },
+      "Action": "sts:AssumeRole",
+      "Condition": {
+        "StringEquals": {
+          "aws:SourceAccount": %q
+        }
+      }
+    }
+  ]
+}`, accountId)
+}
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+type policyStatement struct {
+	Effect   string
+	Action   []string
+	Resource []string
+}
+
+// deliveryRolePolicy returns the inline policy of the role, which writes the
+// backed up records to the bucket and puts records into the delivery stream.
+func deliveryRolePolicy(bucketArn string, deliveryStreamArn string) (string, error) {
+	policy := policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{
+			{
+				Effect: "Allow",
+				Action: []string{
+					"s3:AbortMultipartUpload",
+					"s3:GetBucketLocation",
+					"s3:GetObject",
+					"s3:ListBucket",
+					"s3:ListBucketMultipartUploads",
+					"s3:PutObject",
+				},
+				Resource: []string{bucketArn, bucketArn + "/*"},
+			},
+			{
+				Effect:   "Allow",
+				Action:   []string{"firehose:PutRecord", "firehose:PutRecordBatch"},
+				Resource: []string{deliveryStreamArn},
+			},
+		},
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func deliveryStreamArn(meta interface{}, name string) string {
+	client := meta.(*conns.AWSClient)
+	return fmt.Sprintf("arn:%s:firehose:%s:%s:deliverystream/%s", client.Partition, client.Region, client.AccountID, name)
+}
+
+// ensureDeliveryRole creates the IAM role, or adopts an existing role with
+// the same name, and puts its inline policy. policy_put records that the
+// policy was put, so it is deleted from an adopted role too.
+func ensureDeliveryRole(d *schema.ResourceData, meta interface{}) (string, bool, error) {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	defaultTagsConfig := meta.(*conns.AWSClient).DefaultTagsConfig
+	tags := defaultTagsConfig.MergeTags(tftags.New(d.Get("tags").(map[string]interface{}))).IgnoreAWS()
+	roleName := d.Get("role_name").(string)
+
+	input := &iam.CreateRoleInput{
+		RoleName:                 aws.String(roleName),
+		AssumeRolePolicyDocument: aws.String(deliveryAssumeRolePolicy(meta.(*conns.AWSClient).AccountID)),
+		Description:              aws.String("Allows Kinesis Data Firehose to deliver to Noname and back up to S3."),
+	}
+	for k, v := range tags.Map() {
+		input.Tags = append(input.Tags, &iam.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	created := true
+	var role *iam.Role
+	output, err := iamConn.CreateRole(input)
+	if tfawserr.ErrCodeEquals(err, iam.ErrCodeEntityAlreadyExistsException) {
+		created = false
+		existing, err := iamConn.GetRole(&iam.GetRoleInput{RoleName: aws.String(roleName)})
+		if err != nil {
+			return "", false, fmt.Errorf("reading IAM Role (%s): %w", roleName, err)
+		}
+		role = existing.Role
+	} else if err != nil {
+		return "", false, fmt.Errorf("creating IAM Role (%s): %w", roleName, err)
+	} else {
+		role = output.Role
+	}
+
+	if err := putDeliveryRolePolicy(d, meta); err != nil {
+		return "", created, err
+	}
+	d.Set("policy_put", true)
+
+	return aws.StringValue(role.Arn), created, nil
+}
+
+func putDeliveryRolePolicy(d *schema.ResourceData, meta interface{}) error {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	roleName := d.Get("role_name").(string)
+	policy, err := deliveryRolePolicy(expandS3Backup(d).bucketArn, deliveryStreamArn(meta, d.Get("name").(string)))
+	if err != nil {
+		return err
+	}
+
+	_, err = iamConn.PutRolePolicy(&iam.PutRolePolicyInput{
+		RoleName:       aws.String(roleName),
+		PolicyName:     aws.String(deliveryRolePolicyName),
+		PolicyDocument: aws.String(policy),
+	})
+	if err != nil {
+		return fmt.Errorf("putting IAM Role (%s) policy: %w", roleName, err)
+	}
+	return nil
+}
+
+// undoDeliveryRole releases the role after a failed create and returns the
+// error of the create along with any of the release.
+func undoDeliveryRole(d *schema.ResourceData, meta interface{}, err error) error {
+	if releaseErr := releaseDeliveryRole(d, meta); releaseErr != nil {
+		return multierror.Append(err, releaseErr)
+	}
+	return err
+}
+
+// releaseDeliveryRole deletes the inline policy when the resource put it, and
+// the role when the resource created it. Roles created before policy_put was
+// recorded always had the policy.
+func releaseDeliveryRole(d *schema.ResourceData, meta interface{}) error {
+	iamConn := meta.(*conns.AWSClient).IAMConn
+	roleName := d.Get("role_name").(string)
+
+	if d.Get("policy_put").(bool) || d.Get("role_created").(bool) {
+		_, err := iamConn.DeleteRolePolicy(&iam.DeleteRolePolicyInput{
+			RoleName:   aws.String(roleName),
+			PolicyName: aws.String(deliveryRolePolicyName),
+		})
+		if err != nil && !tfawserr.ErrCodeEquals(err, iam.ErrCodeNoSuchEntityException) {
+			return fmt.Errorf("deleting IAM Role (%s) policy: %w", roleName, err)
+		}
+		d.Set("policy_put", false)
+	}
+
+	if !d.Get("role_created").(bool) {
+		return nil
+	}
+
+	log.Printf("[DEBUG] Deleting IAM Role: %s", roleName)
+	_, err := iamConn.DeleteRole(&iam.DeleteRoleInput{
+		RoleName: aws.String(roleName),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, iam.ErrCodeNoSuchEntityException) {
+		return fmt.Errorf("deleting IAM Role (%s): %w", roleName, err)
+	}
+	d.Set("role_created", false)
+	return nil
+}
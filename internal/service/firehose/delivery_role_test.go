@@ -0,0 +1,228 @@
+package firehose
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+)
+
+func TestDeliveryRolePolicy(t *testing.T) {
+	bucketArn := "arn:aws:s3:::noname-backup"                                                      //lintignore:AWSAT005
+	streamArn := "arn:aws:firehose:us-east-1:123456789012:deliverystream/amazon-apigateway-noname" //lintignore:AWSAT003,AWSAT005
+
+	policy, err := deliveryRolePolicy(bucketArn, streamArn)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var actual policyDocument
+	if err := json.Unmarshal([]byte(policy), &actual); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(actual.Statement) != 2 {
+		t.Fatalf("got %d statements, expected 2", len(actual.Statement))
+	}
+	if expected := []string{bucketArn, bucketArn + "/*"}; !reflect.DeepEqual(actual.Statement[0].Resource, expected) {
+		t.Errorf("got S3 resources %v, expected %v", actual.Statement[0].Resource, expected)
+	}
+	if expected := []string{streamArn}; !reflect.DeepEqual(actual.Statement[1].Resource, expected) {
+		t.Errorf("got Firehose resources %v, expected %v", actual.Statement[1].Resource, expected)
+	}
+}
+
+func TestDefaultDeliveryRoleName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{
+			name:     "amazon-apigateway-noname",
+			expected: "amazon-apigateway-noname-firehose",
+		},
+		{
+			name:     "amazon-apigateway-noname-access-logs-of-every-production-stage",
+			expected: "amazon-apigateway-noname-access-logs-of-every-production-stage-f",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := defaultDeliveryRoleName(tc.name)
+			if actual != tc.expected {
+				t.Errorf("got %s, expected %s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDeliveryAssumeRolePolicy(t *testing.T) {
+	var actual struct {
+		Statement []struct {
+			Condition map[string]map[string]string
+		}
+	}
+	if err := json.Unmarshal([]byte(deliveryAssumeRolePolicy("123456789012")), &actual); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(actual.Statement) != 1 {
+		t.Fatalf("got %d statements, expected 1", len(actual.Statement))
+	}
+	expected := map[string]map[string]string{"StringEquals": {"aws:SourceAccount": "123456789012"}}
+	if !reflect.DeepEqual(actual.Statement[0].Condition, expected) {
+		t.Errorf("got condition %v, expected %v", actual.Statement[0].Condition, expected)
+	}
+}
+
+// fakeDeliveryRole serves the IAM role operations and a failing
+// CreateDeliveryStream, and records the calls changing the role. roleExists
+// makes CreateRole fail as the role already exists.
+type fakeDeliveryRole struct {
+	roleExists bool
+
+	calls []string
+}
+
+func (f *fakeDeliveryRole) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Amz-Target") == "Firehose_20150804.CreateDeliveryStream" {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"%s","message":"Limit exceeded"}`, firehose.ErrCodeLimitExceededException)
+		return
+	}
+
+	r.ParseForm()
+	action := r.Form.Get("Action")
+	switch action {
+	case "CreateRole":
+		f.calls = append(f.calls, action)
+		if f.roleExists {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, `<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>Role exists</Message></Error></ErrorResponse>`, iam.ErrCodeEntityAlreadyExistsException)
+			return
+		}
+		fmt.Fprint(w, `<CreateRoleResponse><CreateRoleResult><Role><Arn>arn:aws:iam::123456789012:role/noname-firehose</Arn></Role></CreateRoleResult></CreateRoleResponse>`)
+	case "GetRole":
+		fmt.Fprint(w, `<GetRoleResponse><GetRoleResult><Role><Arn>arn:aws:iam::123456789012:role/noname-firehose</Arn></Role></GetRoleResult></GetRoleResponse>`)
+	default:
+		f.calls = append(f.calls, action)
+		fmt.Fprintf(w, `<%[1]sResponse></%[1]sResponse>`, action)
+	}
+}
+
+func testDeliveryRoleMeta(endpoint string) *conns.AWSClient {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKID", "SECRET", ""),
+		Endpoint:    aws.String(endpoint),
+		Region:      aws.String("us-east-1"), //lintignore:AWSAT003
+		MaxRetries:  aws.Int(0),
+	}))
+	return &conns.AWSClient{
+		FirehoseConn: firehose.New(sess),
+		IAMConn:      iam.New(sess),
+		AccountID:    "123456789012",
+		Partition:    "aws",
+		Region:       "us-east-1", //lintignore:AWSAT003
+	}
+}
+
+func TestResourceFirehoseDeliveryCreate_role(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fake          *fakeDeliveryRole
+		expectedCalls []string
+	}{
+		{
+			name:          "created role",
+			fake:          &fakeDeliveryRole{},
+			expectedCalls: []string{"CreateRole", "PutRolePolicy", "DeleteRolePolicy", "DeleteRole"},
+		},
+		{
+			name:          "adopted role",
+			fake:          &fakeDeliveryRole{roleExists: true},
+			expectedCalls: []string{"CreateRole", "PutRolePolicy", "DeleteRolePolicy"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.fake)
+			defer server.Close()
+
+			meta := testDeliveryRoleMeta(server.URL)
+
+			d := schema.TestResourceDataRaw(t, ResourceFirehoseDelivery().Schema, map[string]interface{}{
+				"name":         "noname",
+				"endpoint_url": "https://collector.example.com",
+				"access_key":   "key",
+				"s3_backup": []interface{}{map[string]interface{}{
+					"bucket_arn": "arn:aws:s3:::noname-backup", //lintignore:AWSAT005
+				}},
+			})
+			if diags := resourceFirehoseDeliveryCreate(context.Background(), d, meta); !diags.HasError() {
+				t.Fatal("expected an error")
+			}
+			if !reflect.DeepEqual(tc.fake.calls, tc.expectedCalls) {
+				t.Errorf("got calls %v, expected %v", tc.fake.calls, tc.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestReleaseDeliveryRole(t *testing.T) {
+	testCases := []struct {
+		name          string
+		roleCreated   bool
+		policyPut     bool
+		expectedCalls []string
+	}{
+		{
+			name:          "created role",
+			roleCreated:   true,
+			policyPut:     true,
+			expectedCalls: []string{"DeleteRolePolicy", "DeleteRole"},
+		},
+		{
+			name:          "adopted role",
+			policyPut:     true,
+			expectedCalls: []string{"DeleteRolePolicy"},
+		},
+		{
+			name: "unmanaged role",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeDeliveryRole{}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			d := schema.TestResourceDataRaw(t, ResourceFirehoseDelivery().Schema, map[string]interface{}{
+				"name":         "noname",
+				"endpoint_url": "https://collector.example.com",
+				"access_key":   "key",
+				"role_name":    "noname-firehose",
+			})
+			d.Set("role_created", tc.roleCreated)
+			d.Set("policy_put", tc.policyPut)
+
+			if err := releaseDeliveryRole(d, testDeliveryRoleMeta(server.URL)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(fake.calls, tc.expectedCalls) {
+				t.Errorf("got calls %v, expected %v", fake.calls, tc.expectedCalls)
+			}
+		})
+	}
+}
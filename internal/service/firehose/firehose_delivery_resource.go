@@ -0,0 +1,552 @@
+package firehose
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	tftags "github.com/idanhaitner/terraform-provider-noname/internal/tags"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+const (
+	deliveryDefaultEndpointName = "Noname"
+	deliveryRolePropagation     = 2 * time.Minute
+	deliveryStreamTimeout       = 10 * time.Minute
+)
+
+// s3Backup is the bucket receiving the records the HTTP endpoint did not
+// accept, or all records with AllData.
+type s3Backup struct {
+	bucketArn         string
+	prefix            string
+	compressionFormat string
+	backupMode        string
+}
+
+func ResourceFirehoseDelivery() *schema.Resource {
+	return &schema.Resource{
+		Description: `Creates a Kinesis Data Firehose delivery stream delivering to the HTTP endpoint of the Noname collector,
+with an S3 bucket backing up the failed records. The IAM role Firehose assumes is created unless an existing role is given.
+To receive API Gateway access logs directly, as access_log_destination_arn of noname_api_gateway_integration,
+the name must start with amazon-apigateway-.`,
+		ReadContext:   resourceFirehoseDeliveryRead,
+		CreateContext: resourceFirehoseDeliveryCreate,
+		DeleteContext: resourceFirehoseDeliveryDelete,
+		UpdateContext: resourceFirehoseDeliveryUpdate,
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Description: "Name of the delivery stream.",
+				Type:        schema.TypeString,
+				Required:    true,
+				ForceNew:    true,
+				ValidateFunc: validation.All(
+					validation.StringLenBetween(1, 64),
+					validation.StringMatch(regexp.MustCompile(`^[a-zA-Z0-9_.\-]+$`), "must contain only alphanumeric characters, underscores, hyphens and periods"),
+				),
+			},
+			"arn": {
+				Description: "ARN of the delivery stream.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"endpoint_url": {
+				Description:  "HTTPS URL of the Noname collector.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsURLWithHTTPS,
+			},
+			"endpoint_name": {
+				Description: "Name of the HTTP endpoint.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     deliveryDefaultEndpointName,
+			},
+			"access_key": {
+				Description:  "Access key of the Noname collector, sent with every request.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Sensitive:    true,
+				ExactlyOneOf: []string{"access_key", "access_key_secret_arn"},
+			},
+			"access_key_secret_arn": {
+				Description: "ARN of the Secrets Manager secret whose value is the access key of the Noname collector. " +
+					"A new current version of the secret is delivered by the next apply.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: verify.ValidARN,
+			},
+			"access_key_secret_version_id": {
+				Description: "Version of the access key secret the delivery stream sends.",
+				Type:        schema.TypeString,
+				Computed:    true,
+			},
+			"buffering_size": {
+				Description:  "Size in MiB of the records buffered before they are delivered.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      5,
+				ValidateFunc: validation.IntBetween(1, 64),
+			},
+			"buffering_interval": {
+				Description:  "Number of seconds records are buffered before they are delivered.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
+				ValidateFunc: validation.IntBetween(60, 900),
+			},
+			"retry_duration": {
+				Description:  "Number of seconds Firehose retries a failed delivery before backing the records up to S3.",
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
+				ValidateFunc: validation.IntBetween(0, 7200),
+			},
+			"content_encoding": {
+				Description:  "Content encoding of the requests, `GZIP` or `NONE`.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      firehose.ContentEncodingGzip,
+				ValidateFunc: validation.StringInSlice(firehose.ContentEncoding_Values(), false),
+			},
+			"s3_backup": {
+				Description: "S3 bucket backing up the records.",
+				Type:        schema.TypeList,
+				Required:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"bucket_arn": {
+							Description:  "ARN of the S3 bucket.",
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: verify.ValidARN,
+						},
+						"prefix": {
+							Description: "Prefix of the S3 objects.",
+							Type:        schema.TypeString,
+							Optional:    true,
+						},
+						"compression_format": {
+							Description:  "Compression format of the S3 objects.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      firehose.CompressionFormatGzip,
+							ValidateFunc: validation.StringInSlice(firehose.CompressionFormat_Values(), false),
+						},
+						"backup_mode": {
+							Description:  "`FailedDataOnly` to back up the records the HTTP endpoint did not accept, `AllData` to back up all records.",
+							Type:         schema.TypeString,
+							Optional:     true,
+							ForceNew:     true,
+							Default:      firehose.HttpEndpointS3BackupModeFailedDataOnly,
+							ValidateFunc: validation.StringInSlice(firehose.HttpEndpointS3BackupMode_Values(), false),
+						},
+					},
+				},
+			},
+			"role_arn": {
+				Description:  `ARN of an existing IAM role Firehose assumes. A role is created when not set.`,
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: verify.ValidARN,
+			},
+			"role_name": {
+				Description: "Name of the IAM role created when role_arn is not set, `<name>-firehose` by default. An existing role with this name is adopted. " +
+					"CloudWatch Logs can assume the role too, so it can be the role_arn of a noname_log_forwarding to the delivery stream.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringLenBetween(1, 64),
+			},
+			"role_created": {
+				Description: `Whether the IAM role was created by this resource, in which case it is deleted on destroy.`,
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"policy_put": {
+				Description: `Whether the inline policy of the IAM role was put by this resource, in which case it is deleted on destroy.`,
+				Type:        schema.TypeBool,
+				Computed:    true,
+			},
+			"tags": tftags.TagsSchemaForceNew(),
+		},
+	}
+}
+
+func resourceFirehoseDeliveryRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).FirehoseConn
+	stream, err := FindDeliveryStreamByName(conn, d.Id())
+
+	if !d.IsNewResource() && tfresource.NotFound(err) {
+		log.Printf("[WARN] Kinesis Data Firehose Delivery Stream (%s) not found, removing from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	if err != nil {
+		return diag.Errorf("reading Kinesis Data Firehose Delivery Stream (%s): %s", d.Id(), err)
+	}
+
+	destination := httpEndpointDestination(stream)
+	if destination == nil {
+		return diag.Errorf("Kinesis Data Firehose Delivery Stream (%s) has no HTTP endpoint destination", d.Id())
+	}
+
+	d.Set("name", stream.DeliveryStreamName)
+	d.Set("arn", stream.DeliveryStreamARN)
+	d.Set("role_arn", destination.RoleARN)
+	if endpoint := destination.EndpointConfiguration; endpoint != nil {
+		d.Set("endpoint_url", endpoint.Url)
+		d.Set("endpoint_name", endpoint.Name)
+	}
+	if hints := destination.BufferingHints; hints != nil {
+		d.Set("buffering_size", hints.SizeInMBs)
+		d.Set("buffering_interval", hints.IntervalInSeconds)
+	}
+	if options := destination.RetryOptions; options != nil {
+		d.Set("retry_duration", options.DurationInSeconds)
+	}
+	if request := destination.RequestConfiguration; request != nil {
+		d.Set("content_encoding", request.ContentEncoding)
+	}
+	if s3 := destination.S3DestinationDescription; s3 != nil {
+		d.Set("s3_backup", []interface{}{map[string]interface{}{
+			"bucket_arn":         aws.StringValue(s3.BucketARN),
+			"prefix":             aws.StringValue(s3.Prefix),
+			"compression_format": aws.StringValue(s3.CompressionFormat),
+			"backup_mode":        aws.StringValue(destination.S3BackupMode),
+		}})
+	}
+
+	// A new current version of the secret shows up as a change of
+	// access_key_secret_arn, so the next apply delivers it.
+	if secretArn := d.Get("access_key_secret_arn").(string); secretArn != "" {
+		versionId, err := currentSecretVersionId(meta.(*conns.AWSClient).SecretsManagerConn, secretArn)
+		if err != nil {
+			return diag.Errorf("reading Secrets Manager Secret (%s): %s", secretArn, err)
+		}
+		if versionId != d.Get("access_key_secret_version_id").(string) {
+			log.Printf("[WARN] Secrets Manager Secret (%s) has a new version, not delivered by %s yet", secretArn, d.Id())
+			d.Set("access_key_secret_arn", "")
+		}
+	}
+
+	return nil
+}
+
+func resourceFirehoseDeliveryCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).FirehoseConn
+	name := d.Get("name").(string)
+
+	accessKey, err := deliveryAccessKey(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	roleArn := d.Get("role_arn").(string)
+	if roleArn == "" {
+		if d.Get("role_name").(string) == "" {
+			d.Set("role_name", defaultDeliveryRoleName(name))
+		}
+		arn, created, err := ensureDeliveryRole(d, meta)
+		d.Set("role_created", created)
+		if err != nil {
+			return diag.FromErr(undoDeliveryRole(d, meta, err))
+		}
+		roleArn = arn
+		d.Set("role_arn", roleArn)
+	}
+
+	defaultTagsConfig := meta.(*conns.AWSClient).DefaultTagsConfig
+	tags := defaultTagsConfig.MergeTags(tftags.New(d.Get("tags").(map[string]interface{}))).IgnoreAWS()
+	input := &firehose.CreateDeliveryStreamInput{
+		DeliveryStreamName: aws.String(name),
+		DeliveryStreamType: aws.String(firehose.DeliveryStreamTypeDirectPut),
+		HttpEndpointDestinationConfiguration: &firehose.HttpEndpointDestinationConfiguration{
+			EndpointConfiguration: expandHttpEndpointConfiguration(d, accessKey),
+			BufferingHints:        expandHttpEndpointBufferingHints(d),
+			RetryOptions:          expandHttpEndpointRetryOptions(d),
+			RequestConfiguration:  expandHttpEndpointRequestConfiguration(d),
+			RoleARN:               aws.String(roleArn),
+			S3BackupMode:          aws.String(expandS3Backup(d).backupMode),
+			S3Configuration:       expandS3DestinationConfiguration(expandS3Backup(d), roleArn),
+		},
+	}
+	for k, v := range tags.Map() {
+		input.Tags = append(input.Tags, &firehose.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	// A new role takes a while to be assumable by Firehose.
+	log.Printf("[DEBUG] Creating Kinesis Data Firehose Delivery Stream: %s", name)
+	_, err = tfresource.RetryWhenAWSErrMessageContains(deliveryRolePropagation, func() (interface{}, error) {
+		return conn.CreateDeliveryStream(input)
+	}, firehose.ErrCodeInvalidArgumentException, "unable to assume role")
+	if err != nil {
+		return diag.FromErr(undoDeliveryRole(d, meta, fmt.Errorf("creating Kinesis Data Firehose Delivery Stream (%s): %w", name, err)))
+	}
+
+	d.SetId(name)
+	if _, err := waitDeliveryStreamActive(conn, d.Id()); err != nil {
+		return diag.Errorf("waiting for Kinesis Data Firehose Delivery Stream (%s) create: %s", d.Id(), err)
+	}
+
+	return resourceFirehoseDeliveryRead(ctx, d, meta)
+}
+
+func resourceFirehoseDeliveryUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).FirehoseConn
+
+	if (d.Get("role_created").(bool) || d.Get("policy_put").(bool)) && d.HasChange("s3_backup.0.bucket_arn") {
+		if err := putDeliveryRolePolicy(d, meta); err != nil {
+			return diag.FromErr(err)
+		}
+	}
+
+	// The access key is not returned by Firehose, the endpoint is always
+	// updated with the configured one.
+	accessKey, err := deliveryAccessKey(d, meta)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+
+	stream, err := FindDeliveryStreamByName(conn, d.Id())
+	if err != nil {
+		return diag.Errorf("reading Kinesis Data Firehose Delivery Stream (%s): %s", d.Id(), err)
+	}
+	if len(stream.Destinations) == 0 {
+		return diag.Errorf("Kinesis Data Firehose Delivery Stream (%s) has no destination", d.Id())
+	}
+
+	roleArn := d.Get("role_arn").(string)
+	backup := expandS3Backup(d)
+	input := &firehose.UpdateDestinationInput{
+		DeliveryStreamName:             aws.String(d.Id()),
+		CurrentDeliveryStreamVersionId: stream.VersionId,
+		DestinationId:                  stream.Destinations[0].DestinationId,
+		HttpEndpointDestinationUpdate: &firehose.HttpEndpointDestinationUpdate{
+			EndpointConfiguration: expandHttpEndpointConfiguration(d, accessKey),
+			BufferingHints:        expandHttpEndpointBufferingHints(d),
+			RetryOptions:          expandHttpEndpointRetryOptions(d),
+			RequestConfiguration:  expandHttpEndpointRequestConfiguration(d),
+			RoleARN:               aws.String(roleArn),
+			S3Update: &firehose.S3DestinationUpdate{
+				BucketARN:         aws.String(backup.bucketArn),
+				Prefix:            aws.String(backup.prefix),
+				CompressionFormat: aws.String(backup.compressionFormat),
+				RoleARN:           aws.String(roleArn),
+			},
+		},
+	}
+
+	log.Printf("[DEBUG] Updating Kinesis Data Firehose Delivery Stream: %s", d.Id())
+	_, err = tfresource.RetryWhenAWSErrMessageContains(deliveryRolePropagation, func() (interface{}, error) {
+		return conn.UpdateDestination(input)
+	}, firehose.ErrCodeInvalidArgumentException, "unable to assume role")
+	if err != nil {
+		return diag.Errorf("updating Kinesis Data Firehose Delivery Stream (%s): %s", d.Id(), err)
+	}
+
+	return resourceFirehoseDeliveryRead(ctx, d, meta)
+}
+
+func resourceFirehoseDeliveryDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).FirehoseConn
+
+	log.Printf("[DEBUG] Deleting Kinesis Data Firehose Delivery Stream: %s", d.Id())
+	_, err := conn.DeleteDeliveryStream(&firehose.DeleteDeliveryStreamInput{
+		DeliveryStreamName: aws.String(d.Id()),
+	})
+	if err != nil && !tfawserr.ErrCodeEquals(err, firehose.ErrCodeResourceNotFoundException) {
+		return diag.Errorf("deleting Kinesis Data Firehose Delivery Stream (%s): %s", d.Id(), err)
+	}
+
+	if _, err := waitDeliveryStreamDeleted(conn, d.Id()); err != nil {
+		return diag.Errorf("waiting for Kinesis Data Firehose Delivery Stream (%s) delete: %s", d.Id(), err)
+	}
+
+	return diag.FromErr(releaseDeliveryRole(d, meta))
+}
+
+func defaultDeliveryRoleName(name string) string {
+	roleName := name + "-firehose"
+	if len(roleName) > 64 {
+		roleName = roleName[:64]
+	}
+	return roleName
+}
+
+// deliveryAccessKey returns the configured access key, or the current value
+// of the access key secret, whose version is then kept in
+// access_key_secret_version_id.
+func deliveryAccessKey(d *schema.ResourceData, meta interface{}) (string, error) {
+	secretArn := d.Get("access_key_secret_arn").(string)
+	if secretArn == "" {
+		d.Set("access_key_secret_version_id", "")
+		return d.Get("access_key").(string), nil
+	}
+
+	conn := meta.(*conns.AWSClient).SecretsManagerConn
+	output, err := conn.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretArn),
+	})
+	if err != nil {
+		return "", fmt.Errorf("reading Secrets Manager Secret (%s) value: %w", secretArn, err)
+	}
+
+	d.Set("access_key_secret_version_id", output.VersionId)
+	return aws.StringValue(output.SecretString), nil
+}
+
+// currentSecretVersionId returns the version of the secret labeled
+// AWSCURRENT.
+func currentSecretVersionId(conn *secretsmanager.SecretsManager, secretArn string) (string, error) {
+	output, err := conn.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretArn),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for versionId, stages := range output.VersionIdsToStages {
+		for _, stage := range stages {
+			if aws.StringValue(stage) == "AWSCURRENT" {
+				return versionId, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+func httpEndpointDestination(stream *firehose.DeliveryStreamDescription) *firehose.HttpEndpointDestinationDescription {
+	for _, destination := range stream.Destinations {
+		if destination.HttpEndpointDestinationDescription != nil {
+			return destination.HttpEndpointDestinationDescription
+		}
+	}
+	return nil
+}
+
+func expandHttpEndpointConfiguration(d *schema.ResourceData, accessKey string) *firehose.HttpEndpointConfiguration {
+	return &firehose.HttpEndpointConfiguration{
+		Url:       aws.String(d.Get("endpoint_url").(string)),
+		Name:      aws.String(d.Get("endpoint_name").(string)),
+		AccessKey: aws.String(accessKey),
+	}
+}
+
+func expandHttpEndpointBufferingHints(d *schema.ResourceData) *firehose.HttpEndpointBufferingHints {
+	return &firehose.HttpEndpointBufferingHints{
+		SizeInMBs:         aws.Int64(int64(d.Get("buffering_size").(int))),
+		IntervalInSeconds: aws.Int64(int64(d.Get("buffering_interval").(int))),
+	}
+}
+
+func expandHttpEndpointRetryOptions(d *schema.ResourceData) *firehose.HttpEndpointRetryOptions {
+	return &firehose.HttpEndpointRetryOptions{
+		DurationInSeconds: aws.Int64(int64(d.Get("retry_duration").(int))),
+	}
+}
+
+func expandHttpEndpointRequestConfiguration(d *schema.ResourceData) *firehose.HttpEndpointRequestConfiguration {
+	return &firehose.HttpEndpointRequestConfiguration{
+		ContentEncoding: aws.String(d.Get("content_encoding").(string)),
+	}
+}
+
+func expandS3Backup(d *schema.ResourceData) s3Backup {
+	backup := s3Backup{
+		compressionFormat: firehose.CompressionFormatGzip,
+		backupMode:        firehose.HttpEndpointS3BackupModeFailedDataOnly,
+	}
+	if l, ok := d.Get("s3_backup").([]interface{}); ok && len(l) > 0 && l[0] != nil {
+		tfMap := l[0].(map[string]interface{})
+		backup.bucketArn = tfMap["bucket_arn"].(string)
+		backup.prefix = tfMap["prefix"].(string)
+		backup.compressionFormat = tfMap["compression_format"].(string)
+		backup.backupMode = tfMap["backup_mode"].(string)
+	}
+	return backup
+}
+
+func expandS3DestinationConfiguration(backup s3Backup, roleArn string) *firehose.S3DestinationConfiguration {
+	config := &firehose.S3DestinationConfiguration{
+		BucketARN:         aws.String(backup.bucketArn),
+		CompressionFormat: aws.String(backup.compressionFormat),
+		RoleARN:           aws.String(roleArn),
+	}
+	if backup.prefix != "" {
+		config.Prefix = aws.String(backup.prefix)
+	}
+	return config
+}
+
+func statusDeliveryStream(conn *firehose.Firehose, name string) resource.StateRefreshFunc {
+	return func() (interface{}, string, error) {
+		output, err := FindDeliveryStreamByName(conn, name)
+
+		if tfresource.NotFound(err) {
+			return nil, "", nil
+		}
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		return output, aws.StringValue(output.DeliveryStreamStatus), nil
+	}
+}
+
+func waitDeliveryStreamActive(conn *firehose.Firehose, name string) (*firehose.DeliveryStreamDescription, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{firehose.DeliveryStreamStatusCreating},
+		Target:       []string{firehose.DeliveryStreamStatusActive},
+		Refresh:      statusDeliveryStream(conn, name),
+		PollInterval: 10 * time.Second,
+		Timeout:      deliveryStreamTimeout,
+	}
+
+	outputRaw, err := stateConf.WaitForState()
+
+	if output, ok := outputRaw.(*firehose.DeliveryStreamDescription); ok {
+		if failure := output.FailureDescription; failure != nil {
+			tfresource.SetLastError(err, fmt.Errorf("%s: %s", aws.StringValue(failure.Type), aws.StringValue(failure.Details)))
+		}
+
+		return output, err
+	}
+
+	return nil, err
+}
+
+func waitDeliveryStreamDeleted(conn *firehose.Firehose, name string) (*firehose.DeliveryStreamDescription, error) {
+	stateConf := &resource.StateChangeConf{
+		Pending:      []string{firehose.DeliveryStreamStatusDeleting, firehose.DeliveryStreamStatusActive},
+		Target:       []string{},
+		Refresh:      statusDeliveryStream(conn, name),
+		PollInterval: 10 * time.Second,
+		Timeout:      deliveryStreamTimeout,
+	}
+
+	outputRaw, err := stateConf.WaitForState()
+
+	if output, ok := outputRaw.(*firehose.DeliveryStreamDescription); ok {
+		return output, err
+	}
+
+	return nil, err
+}
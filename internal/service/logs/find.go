@@ -0,0 +1,40 @@
+package logs
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+// FindSubscriptionFiltersByLogGroupName returns the subscription filters of
+// the log group, or a NotFoundError when the log group does not exist.
+func FindSubscriptionFiltersByLogGroupName(conn *cloudwatchlogs.CloudWatchLogs, logGroupName string) ([]*cloudwatchlogs.SubscriptionFilter, error) {
+	input := &cloudwatchlogs.DescribeSubscriptionFiltersInput{
+		LogGroupName: aws.String(logGroupName),
+	}
+	var result []*cloudwatchlogs.SubscriptionFilter
+
+	err := conn.DescribeSubscriptionFiltersPages(input, func(page *cloudwatchlogs.DescribeSubscriptionFiltersOutput, lastPage bool) bool {
+		if page == nil {
+			return !lastPage
+		}
+
+		result = append(result, page.SubscriptionFilters...)
+
+		return !lastPage
+	})
+
+	if tfawserr.ErrCodeEquals(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+		return nil, &resource.NotFoundError{
+			LastError:   err,
+			LastRequest: input,
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,350 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/firehose"
+	"github.com/hashicorp/aws-sdk-go-base/v2/awsv1shim/v2/tfawserr"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	"github.com/idanhaitner/terraform-provider-noname/internal/tfresource"
+	"github.com/idanhaitner/terraform-provider-noname/internal/verify"
+)
+
+const (
+	logGroupStatusSubscribed = "SUBSCRIBED"
+	logGroupStatusMissing    = "MISSING"
+	logGroupStatusUnmanaged  = "UNMANAGED"
+	logGroupStatusDrifted    = "DRIFTED"
+)
+
+// subscriptionFilterPropagation is how long a new IAM role, or a new policy
+// of the destination, may take before CloudWatch Logs can deliver to it.
+const subscriptionFilterPropagation = 2 * time.Minute
+
+// subscriptionFilter is the subscription filter put on every log group.
+type subscriptionFilter struct {
+	name           string
+	destinationArn string
+	roleArn        string
+	filterPattern  string
+}
+
+func ResourceLogForwarding() *schema.Resource {
+	return &schema.Resource{
+		Description: `Forwards CloudWatch Logs log groups, such as the execution and access log groups of noname_api_gateway_integration,
+to a Kinesis Data Firehose delivery stream or a cross-account CloudWatch Logs destination with a subscription filter on each log group.
+Only the subscription filters created by this resource are removed on destroy.`,
+		ReadContext:   resourceLogForwardingRead,
+		CreateContext: resourceLogForwardingCreate,
+		DeleteContext: resourceLogForwardingDelete,
+		UpdateContext: resourceLogForwardingUpdate,
+
+		CustomizeDiff: resourceLogForwardingDestinationDiff,
+
+		Schema: map[string]*schema.Schema{
+			"name": {
+				Description:  "Name of the subscription filters.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringLenBetween(1, 512),
+			},
+			"log_group_names": {
+				Description: "Names of the log groups to forward. Log groups that do not exist yet get their subscription filter on the first apply after they are created.",
+				Type:        schema.TypeSet,
+				Required:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"destination_arn": {
+				Description:  "ARN of the Kinesis Data Firehose delivery stream or of the cross-account CloudWatch Logs destination receiving the log events.",
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validLogForwardingDestinationArn,
+			},
+			"role_arn": {
+				Description:  "ARN of the IAM role CloudWatch Logs assumes to put records into the delivery stream. Required with a Kinesis Data Firehose destination.",
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: verify.ValidARN,
+			},
+			"filter_pattern": {
+				Description: "Filter pattern of the forwarded log events. All log events are forwarded when empty.",
+				Type:        schema.TypeString,
+				Optional:    true,
+				Default:     "",
+			},
+			"managed_log_groups": {
+				Description: "Names of the log groups whose subscription filter was created by this resource.",
+				Type:        schema.TypeSet,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+				Computed:    true,
+			},
+			"log_group_status": {
+				Description: "Status of each log group.",
+				Type:        schema.TypeList,
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"log_group_name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"status": {
+							Description: "`SUBSCRIBED` when the log group is forwarded, `MISSING` when the log group does not exist, " +
+								"`UNMANAGED` when the log group has a subscription filter of the same name not created by this resource, " +
+								"which is left alone, or `DRIFTED` when its subscription filter was removed or changed since the last apply.",
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// validLogForwardingDestinationArn accepts the ARN of a Kinesis Data Firehose
+// delivery stream or of a CloudWatch Logs destination.
+func validLogForwardingDestinationArn(v interface{}, k string) (ws []string, errors []error) {
+	ws, errors = verify.ValidARN(v, k)
+	if len(errors) > 0 {
+		return ws, errors
+	}
+
+	value, _ := arn.Parse(v.(string))
+	switch {
+	case value.Service == firehose.ServiceName && strings.HasPrefix(value.Resource, "deliverystream/"):
+	case value.Service == cloudwatchlogs.ServiceName && strings.HasPrefix(value.Resource, "destination:"):
+	default:
+		errors = append(errors, fmt.Errorf("%q must be the ARN of a Kinesis Data Firehose delivery stream or of a CloudWatch Logs destination, got: %s", k, v))
+	}
+	return ws, errors
+}
+
+// resourceLogForwardingDestinationDiff requires role_arn with a Kinesis Data
+// Firehose destination, which CloudWatch Logs only reaches through a role.
+func resourceLogForwardingDestinationDiff(_ context.Context, d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("destination_arn") || !d.NewValueKnown("role_arn") {
+		return nil
+	}
+
+	destinationArn, err := arn.Parse(d.Get("destination_arn").(string))
+	if err != nil {
+		return nil
+	}
+	if destinationArn.Service == firehose.ServiceName && d.Get("role_arn").(string) == "" {
+		return fmt.Errorf("role_arn is required with a Kinesis Data Firehose destination (%s)", destinationArn)
+	}
+	return nil
+}
+
+func expandSubscriptionFilter(d *schema.ResourceData) subscriptionFilter {
+	return subscriptionFilter{
+		name:           d.Get("name").(string),
+		destinationArn: d.Get("destination_arn").(string),
+		roleArn:        d.Get("role_arn").(string),
+		filterPattern:  d.Get("filter_pattern").(string),
+	}
+}
+
+// matches reports whether an existing subscription filter of the same name
+// forwards like the configured one.
+func (config subscriptionFilter) matches(filter *cloudwatchlogs.SubscriptionFilter) bool {
+	return aws.StringValue(filter.DestinationArn) == config.destinationArn &&
+		aws.StringValue(filter.RoleArn) == config.roleArn &&
+		aws.StringValue(filter.FilterPattern) == config.filterPattern
+}
+
+func findSubscriptionFilter(filters []*cloudwatchlogs.SubscriptionFilter, name string) *cloudwatchlogs.SubscriptionFilter {
+	for _, filter := range filters {
+		if aws.StringValue(filter.FilterName) == name {
+			return filter
+		}
+	}
+	return nil
+}
+
+// logGroupStatus returns the status of a log group from its subscription
+// filters. exists is false when the log group does not exist, managed is
+// true when its subscription filter was created by the resource.
+func logGroupStatus(filters []*cloudwatchlogs.SubscriptionFilter, exists bool, managed bool, config subscriptionFilter) string {
+	if !exists {
+		return logGroupStatusMissing
+	}
+
+	filter := findSubscriptionFilter(filters, config.name)
+	switch {
+	case filter != nil && !managed:
+		return logGroupStatusUnmanaged
+	case filter == nil || !config.matches(filter):
+		return logGroupStatusDrifted
+	default:
+		return logGroupStatusSubscribed
+	}
+}
+
+func resourceLogForwardingRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).LogsConn
+	config := expandSubscriptionFilter(d)
+	managedLogGroups := d.Get("managed_log_groups").(*schema.Set)
+	logGroupNames := d.Get("log_group_names").(*schema.Set)
+
+	// A drifted log group is left out of log_group_names, so the next plan
+	// shows it being added back and Update puts its subscription filter again.
+	forwardedLogGroupNames := schema.NewSet(schema.HashString, nil)
+	status := []interface{}{}
+	for _, name := range sortedLogGroupNames(logGroupNames) {
+		filters, err := FindSubscriptionFiltersByLogGroupName(conn, name)
+		if err != nil && !tfresource.NotFound(err) {
+			return diag.Errorf("reading CloudWatch Logs Log Group (%s) subscription filters: %s", name, err)
+		}
+
+		// The subscription filter of a deleted log group is gone with it.
+		exists := err == nil
+		if !exists {
+			log.Printf("[WARN] CloudWatch Logs Log Group (%s) not found, not forwarded by %s", name, d.Id())
+			managedLogGroups.Remove(name)
+		}
+
+		s := logGroupStatus(filters, exists, managedLogGroups.Contains(name), config)
+		if s != logGroupStatusDrifted {
+			forwardedLogGroupNames.Add(name)
+		}
+		status = append(status, map[string]interface{}{
+			"log_group_name": name,
+			"status":         s,
+		})
+	}
+
+	d.Set("log_group_names", forwardedLogGroupNames)
+	d.Set("managed_log_groups", managedLogGroups)
+	d.Set("log_group_status", status)
+	return nil
+}
+
+func resourceLogForwardingCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).LogsConn
+	d.SetId(d.Get("name").(string))
+
+	diags := putSubscriptionFilters(d, conn, expandSubscriptionFilter(d), d.Get("log_group_names").(*schema.Set))
+	return append(diags, resourceLogForwardingRead(ctx, d, meta)...)
+}
+
+func resourceLogForwardingUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).LogsConn
+	config := expandSubscriptionFilter(d)
+
+	o, n := d.GetChange("log_group_names")
+	os, ns := o.(*schema.Set), n.(*schema.Set)
+	diags := deleteSubscriptionFilters(d, conn, config.name, os.Difference(ns))
+
+	added := ns.Difference(os)
+	if d.HasChanges("destination_arn", "role_arn", "filter_pattern") {
+		added = ns
+	}
+	diags = append(diags, putSubscriptionFilters(d, conn, config, added)...)
+	return append(diags, resourceLogForwardingRead(ctx, d, meta)...)
+}
+
+func resourceLogForwardingDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	conn := meta.(*conns.AWSClient).LogsConn
+	return deleteSubscriptionFilters(d, conn, d.Get("name").(string), d.Get("managed_log_groups").(*schema.Set))
+}
+
+// putSubscriptionFilters puts the subscription filter on the log groups and
+// adds them to managed_log_groups. Missing log groups and log groups with a
+// subscription filter of the same name not created by the resource are
+// skipped. A failing log group does not stop the others.
+func putSubscriptionFilters(d *schema.ResourceData, conn *cloudwatchlogs.CloudWatchLogs, config subscriptionFilter, logGroupNames *schema.Set) diag.Diagnostics {
+	var diags diag.Diagnostics
+	managedLogGroups := d.Get("managed_log_groups").(*schema.Set)
+	for _, name := range sortedLogGroupNames(logGroupNames) {
+		filters, err := FindSubscriptionFiltersByLogGroupName(conn, name)
+		if tfresource.NotFound(err) {
+			log.Printf("[WARN] CloudWatch Logs Log Group (%s) not found, skipping", name)
+			continue
+		}
+		if err != nil {
+			diags = append(diags, diag.Errorf("reading CloudWatch Logs Log Group (%s) subscription filters: %s", name, err)...)
+			continue
+		}
+		if !managedLogGroups.Contains(name) && findSubscriptionFilter(filters, config.name) != nil {
+			log.Printf("[WARN] CloudWatch Logs Log Group (%s) has a subscription filter (%s) not created by this resource, skipping", name, config.name)
+			continue
+		}
+
+		if err := putSubscriptionFilter(conn, name, config); err != nil {
+			diags = append(diags, diag.Errorf("putting CloudWatch Logs Subscription Filter (%s) on Log Group (%s): %s", config.name, name, err)...)
+			continue
+		}
+		managedLogGroups.Add(name)
+	}
+
+	d.Set("managed_log_groups", managedLogGroups)
+	return diags
+}
+
+func putSubscriptionFilter(conn *cloudwatchlogs.CloudWatchLogs, logGroupName string, config subscriptionFilter) error {
+	input := &cloudwatchlogs.PutSubscriptionFilterInput{
+		LogGroupName:   aws.String(logGroupName),
+		FilterName:     aws.String(config.name),
+		DestinationArn: aws.String(config.destinationArn),
+		FilterPattern:  aws.String(config.filterPattern),
+	}
+	if config.roleArn != "" {
+		input.RoleArn = aws.String(config.roleArn)
+	}
+
+	log.Printf("[DEBUG] Putting CloudWatch Logs Subscription Filter: %s", input)
+	_, err := tfresource.RetryWhenAWSErrMessageContains(subscriptionFilterPropagation, func() (interface{}, error) {
+		return conn.PutSubscriptionFilter(input)
+	}, cloudwatchlogs.ErrCodeInvalidParameterException, "Could not deliver test message")
+	return err
+}
+
+// deleteSubscriptionFilters deletes the subscription filter from the log
+// groups it was created on by the resource and removes them from
+// managed_log_groups.
+func deleteSubscriptionFilters(d *schema.ResourceData, conn *cloudwatchlogs.CloudWatchLogs, filterName string, logGroupNames *schema.Set) diag.Diagnostics {
+	var diags diag.Diagnostics
+	managedLogGroups := d.Get("managed_log_groups").(*schema.Set)
+	for _, name := range sortedLogGroupNames(logGroupNames) {
+		if !managedLogGroups.Contains(name) {
+			continue
+		}
+
+		log.Printf("[DEBUG] Deleting CloudWatch Logs Subscription Filter (%s) from Log Group: %s", filterName, name)
+		_, err := conn.DeleteSubscriptionFilter(&cloudwatchlogs.DeleteSubscriptionFilterInput{
+			LogGroupName: aws.String(name),
+			FilterName:   aws.String(filterName),
+		})
+		if err != nil && !tfawserr.ErrCodeEquals(err, cloudwatchlogs.ErrCodeResourceNotFoundException) {
+			diags = append(diags, diag.Errorf("deleting CloudWatch Logs Subscription Filter (%s) from Log Group (%s): %s", filterName, name, err)...)
+			continue
+		}
+		managedLogGroups.Remove(name)
+	}
+
+	d.Set("managed_log_groups", managedLogGroups)
+	return diags
+}
+
+func sortedLogGroupNames(s *schema.Set) []string {
+	names := make([]string, 0, s.Len())
+	for _, v := range s.List() {
+		names = append(names, v.(string))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,109 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+func TestLogGroupStatus(t *testing.T) {
+	config := subscriptionFilter{
+		name:           "noname",
+		destinationArn: "arn:aws:firehose:us-east-1:123456789012:deliverystream/noname", //lintignore:AWSAT003,AWSAT005
+		roleArn:        "arn:aws:iam::123456789012:role/noname-logs",                    //lintignore:AWSAT005
+	}
+	filter := &cloudwatchlogs.SubscriptionFilter{
+		FilterName:     aws.String(config.name),
+		DestinationArn: aws.String(config.destinationArn),
+		RoleArn:        aws.String(config.roleArn),
+		FilterPattern:  aws.String(""),
+	}
+	changed := &cloudwatchlogs.SubscriptionFilter{
+		FilterName:     aws.String(config.name),
+		DestinationArn: aws.String(config.destinationArn),
+		RoleArn:        aws.String(config.roleArn),
+		FilterPattern:  aws.String("ERROR"),
+	}
+	other := &cloudwatchlogs.SubscriptionFilter{
+		FilterName:     aws.String("other"),
+		DestinationArn: aws.String(config.destinationArn),
+	}
+
+	testCases := []struct {
+		name     string
+		filters  []*cloudwatchlogs.SubscriptionFilter
+		exists   bool
+		managed  bool
+		expected string
+	}{
+		{
+			name:     "subscribed",
+			filters:  []*cloudwatchlogs.SubscriptionFilter{other, filter},
+			exists:   true,
+			managed:  true,
+			expected: logGroupStatusSubscribed,
+		},
+		{
+			name:     "missing log group",
+			managed:  true,
+			expected: logGroupStatusMissing,
+		},
+		{
+			name:     "filter of the same name not created",
+			filters:  []*cloudwatchlogs.SubscriptionFilter{filter},
+			exists:   true,
+			expected: logGroupStatusUnmanaged,
+		},
+		{
+			name:     "filter removed",
+			filters:  []*cloudwatchlogs.SubscriptionFilter{other},
+			exists:   true,
+			managed:  true,
+			expected: logGroupStatusDrifted,
+		},
+		{
+			name:     "filter changed",
+			filters:  []*cloudwatchlogs.SubscriptionFilter{changed},
+			exists:   true,
+			managed:  true,
+			expected: logGroupStatusDrifted,
+		},
+		{
+			name:     "log group created since",
+			exists:   true,
+			expected: logGroupStatusDrifted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := logGroupStatus(tc.filters, tc.exists, tc.managed, config)
+			if actual != tc.expected {
+				t.Errorf("got %s, expected %s", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestValidLogForwardingDestinationArn(t *testing.T) {
+	testCases := []struct {
+		value     string
+		expectErr bool
+	}{
+		{value: "arn:aws:firehose:us-east-1:123456789012:deliverystream/noname"},         //lintignore:AWSAT003,AWSAT005
+		{value: "arn:aws:logs:us-east-1:123456789012:destination:noname"},                //lintignore:AWSAT003,AWSAT005
+		{value: "arn:aws:logs:us-east-1:123456789012:log-group:noname", expectErr: true}, //lintignore:AWSAT003,AWSAT005
+		{value: "arn:aws:kinesis:us-east-1:123456789012:stream/noname", expectErr: true}, //lintignore:AWSAT003,AWSAT005
+		{value: "noname", expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			_, errs := validLogForwardingDestinationArn(tc.value, "destination_arn")
+			if tc.expectErr != (len(errs) > 0) {
+				t.Errorf("got errors %v, expected error: %t", errs, tc.expectErr)
+			}
+		})
+	}
+}
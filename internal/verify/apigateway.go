@@ -0,0 +1,141 @@
+package verify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// apiGatewayContextVariables are the $context variables available to REST API access logging.
+// https://docs.aws.amazon.com/apigateway/latest/developerguide/api-gateway-mapping-template-reference.html#context-variable-reference
+var apiGatewayContextVariables = []string{
+	"accountId",
+	"apiId",
+	"authenticate.error",
+	"authenticate.latency",
+	"authenticate.status",
+	"authorize.error",
+	"authorize.latency",
+	"authorize.status",
+	"authorizer.error",
+	"authorizer.integrationLatency",
+	"authorizer.integrationStatus",
+	"authorizer.latency",
+	"authorizer.principalId",
+	"authorizer.requestId",
+	"authorizer.status",
+	"awsEndpointRequestId",
+	"customDomain.basePathMatched",
+	"deploymentId",
+	"domainName",
+	"domainPrefix",
+	"error.message",
+	"error.messageString",
+	"error.responseType",
+	"error.validationErrorString",
+	"extendedRequestId",
+	"httpMethod",
+	"identity.accountId",
+	"identity.apiKey",
+	"identity.apiKeyId",
+	"identity.caller",
+	"identity.clientCert.clientCertPem",
+	"identity.clientCert.issuerDN",
+	"identity.clientCert.serialNumber",
+	"identity.clientCert.subjectDN",
+	"identity.clientCert.validity.notAfter",
+	"identity.clientCert.validity.notBefore",
+	"identity.cognitoAuthenticationProvider",
+	"identity.cognitoAuthenticationType",
+	"identity.cognitoIdentityId",
+	"identity.cognitoIdentityPoolId",
+	"identity.principalOrgId",
+	"identity.sourceIp",
+	"identity.user",
+	"identity.userAgent",
+	"identity.userArn",
+	"identity.vpcId",
+	"identity.vpceId",
+	"integration.error",
+	"integration.integrationStatus",
+	"integration.latency",
+	"integration.requestId",
+	"integration.status",
+	"integrationLatency",
+	"integrationStatus",
+	"isCanaryRequest",
+	"path",
+	"protocol",
+	"requestId",
+	"requestTime",
+	"requestTimeEpoch",
+	"resourceId",
+	"resourcePath",
+	"responseLatency",
+	"responseLength",
+	"responseOverride.status",
+	"stage",
+	"status",
+	"waf.error",
+	"waf.latency",
+	"waf.status",
+	"wafResponseCode",
+	"webaclArn",
+	"xrayTraceId",
+}
+
+// apiGatewayContextVariablePrefixes are the $context variables ending with a user defined key,
+// e.g. $context.authorizer.claims.email or $context.requestOverride.header.Accept.
+var apiGatewayContextVariablePrefixes = []string{
+	"authorizer.",
+	"requestOverride.header.",
+	"requestOverride.path.",
+	"requestOverride.querystring.",
+	"responseOverride.header.",
+}
+
+var apiGatewayContextVariableRegexp = regexp.MustCompile(`\$context\.[A-Za-z0-9_.]+`)
+
+// ValidAPIGatewayAccessLogFormat validates an API Gateway REST API access log format.
+// The format must be a single line, only reference known $context variables and include
+// $context.requestId.
+func ValidAPIGatewayAccessLogFormat(v interface{}, k string) (ws []string, errors []error) {
+	value := v.(string)
+
+	if strings.ContainsAny(value, "\r\n") {
+		errors = append(errors, fmt.Errorf("%q must be a single line", k))
+	}
+
+	hasRequestId := false
+	for _, match := range apiGatewayContextVariableRegexp.FindAllString(value, -1) {
+		variable := strings.TrimSuffix(strings.TrimPrefix(match, "$context."), ".")
+		if variable == "requestId" {
+			hasRequestId = true
+		}
+		if !isAPIGatewayContextVariable(variable) {
+			errors = append(errors, fmt.Errorf("%q references an unknown variable: $context.%s", k, variable))
+		}
+	}
+
+	if !hasRequestId {
+		errors = append(errors, fmt.Errorf("%q must include $context.requestId", k))
+	}
+
+	return
+}
+
+func isAPIGatewayContextVariable(variable string) bool {
+	for _, v := range apiGatewayContextVariables {
+		if variable == v {
+			return true
+		}
+	}
+
+	for _, prefix := range apiGatewayContextVariablePrefixes {
+		if strings.HasPrefix(variable, prefix) && len(variable) > len(prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package verify
+
+import (
+	"testing"
+)
+
+func TestValidAPIGatewayAccessLogFormat(t *testing.T) {
+	validFormats := []string{
+		`$context.requestId`,
+		`{"requestId":"$context.requestId","principalId":"$context.authorizer.principalId","latency":"$context.integrationLatency"}`,
+		`$context.identity.sourceIp [$context.requestTime] "$context.httpMethod $context.resourcePath" $context.status $context.requestId.`,
+		`$context.requestId,$context.authorizer.claims.email,$context.requestOverride.header.Accept`,
+	}
+	for _, v := range validFormats {
+		if _, errors := ValidAPIGatewayAccessLogFormat(v, "access_log_format"); len(errors) != 0 {
+			t.Fatalf("%q should be a valid access log format: %q", v, errors)
+		}
+	}
+
+	invalidFormats := []string{
+		``,
+		`$context.status`,
+		`$context.requestId $context.requestID`,
+		`$context.requestId $context.authorizer.`,
+		"$context.requestId\n$context.status",
+	}
+	for _, v := range invalidFormats {
+		if _, errors := ValidAPIGatewayAccessLogFormat(v, "access_log_format"); len(errors) == 0 {
+			t.Fatalf("%q should be an invalid access log format", v)
+		}
+	}
+}
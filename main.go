@@ -4,12 +4,20 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
 
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tf5server"
 	"github.com/idanhaitner/terraform-provider-noname/internal/provider"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == restoreCommandName {
+		if err := runRestoreCommand(context.Background(), os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	debugFlag := flag.Bool("debug", false, "Start provider in debug mode.")
 	flag.Parse()
 
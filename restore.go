@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	awsbase "github.com/hashicorp/aws-sdk-go-base/v2"
+	"github.com/idanhaitner/terraform-provider-noname/internal/conns"
+	apigatewayintegration "github.com/idanhaitner/terraform-provider-noname/internal/service/apigateway-integration"
+)
+
+const restoreCommandName = "restore"
+
+// runRestoreCommand strips the Noname logging configuration from REST APIs
+// without Terraform:
+//
+//	terraform-provider-noname restore [flags] <rest_api_id>...
+//
+// Credentials are resolved like the provider's, from the environment, shared
+// configuration files and the flags.
+func runRestoreCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet(restoreCommandName, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags] <rest_api_id>...\n\n", os.Args[0], restoreCommandName)
+		fmt.Fprintln(flags.Output(), "Restores the stage settings saved by noname_api_gateway_integration before it configured them.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	stateFile := flags.String("state", "", "Terraform state file holding the snapshots of the integration.")
+	snapshotStore := flags.String("snapshot-store", "", "Snapshot store holding the snapshots, ssm[:<prefix>], stage_tag or file:<directory>.")
+	dryRun := flags.Bool("dry-run", false, "Print the stage updates instead of making them.")
+	parallelism := flags.Int("parallelism", 10, "Number of REST APIs restored concurrently.")
+	region := flags.String("region", "", "AWS region of the REST APIs.")
+	profile := flags.String("profile", "", "AWS profile of the shared configuration files.")
+	assumeRoleARN := flags.String("assume-role-arn", "", "ARN of an IAM role to assume.")
+	externalID := flags.String("external-id", "", "External ID used to assume the role.")
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no REST API IDs given")
+	}
+
+	config := conns.Config{
+		Endpoints:  make(map[string]string),
+		MaxRetries: 25,
+		Profile:    *profile,
+		Region:     *region,
+	}
+	if *assumeRoleARN != "" {
+		config.AssumeRole = &awsbase.AssumeRole{
+			RoleARN:    *assumeRoleARN,
+			ExternalID: *externalID,
+		}
+	}
+
+	client, diags := config.Client(ctx)
+	if diags.HasError() {
+		var msgs []string
+		for _, d := range diags {
+			msgs = append(msgs, d.Summary)
+		}
+		return errors.New(strings.Join(msgs, ", "))
+	}
+
+	return apigatewayintegration.RestoreCommand(client.(*conns.AWSClient), apigatewayintegration.RestoreCommandInput{
+		RestApiIds:    flags.Args(),
+		StateFile:     *stateFile,
+		SnapshotStore: *snapshotStore,
+		Parallelism:   *parallelism,
+		DryRun:        *dryRun,
+	}, os.Stdout)
+}